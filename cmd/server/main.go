@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"gopkg.in/yaml.v3"
@@ -11,8 +12,12 @@ import (
 	"strings"
 	"time"
 
+	"mail-manager/internal/archive"
 	"mail-manager/internal/auth"
+	"mail-manager/internal/bounce"
 	"mail-manager/internal/email"
+	"mail-manager/internal/job"
+	"mail-manager/internal/suppression"
 	"mail-manager/internal/web"
 )
 
@@ -32,16 +37,289 @@ type Config struct {
 		ApiToken string `yaml:"api_token"`
 	} `yaml:"authentik"`
 	SMTP struct {
-		Host     string `yaml:"host"`
-		Port     int    `yaml:"port"`
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-		From     string `yaml:"from"`
+		SMTPAccountConfig `yaml:",inline"`
+		// 발신자별로 사용할 추가 SMTP 계정, smtp 바로 아래 설정은 "default" 계정입니다.
+		Accounts []struct {
+			Name              string `yaml:"name"`
+			SMTPAccountConfig `yaml:",inline"`
+		} `yaml:"accounts"`
 	} `yaml:"smtp"`
+	// 작업별로 선택할 수 있는 발신자, users/groups가 비어 있으면 모든 사용자가 사용할 수 있습니다.
+	Senders []struct {
+		Name        string   `yaml:"name"`
+		DisplayName string   `yaml:"display_name"`
+		Address     string   `yaml:"address"`
+		Account     string   `yaml:"account"`
+		Users       []string `yaml:"users"`
+		Groups      []string `yaml:"groups"`
+	} `yaml:"senders"`
+	// 관리자 API(/api/admin/...)를 사용할 수 있는 사용자와 그룹, 비어 있으면 아무도 사용할 수 없습니다.
+	Admins struct {
+		Users  []string `yaml:"users"`
+		Groups []string `yaml:"groups"`
+	} `yaml:"admins"`
 	Templates struct {
 		Email string `yaml:"email"`
 		Image string `yaml:"image"`
 	} `yaml:"templates"`
+	Jobs struct {
+		Dir               string        `yaml:"dir"`
+		Workers           int           `yaml:"workers"`
+		IdempotencyWindow time.Duration `yaml:"idempotency_window"`
+		// 발송한 메일 원문(.eml)을 작업별로 보관
+		ArchiveMessages bool `yaml:"archive_messages"`
+	} `yaml:"jobs"`
+	// 발송 기록 검색용 보관소, dir을 비우면 사용하지 않음
+	Archive struct {
+		Dir string `yaml:"dir"`
+	} `yaml:"archive"`
+	// 발송 제외(suppression) 목록 파일
+	Suppression struct {
+		File string `yaml:"file"`
+	} `yaml:"suppression"`
+	// 반송 메일(DSN) 처리, source를 비우면 사용하지 않음
+	Bounces struct {
+		// imap, mbox, maildir
+		Source string `yaml:"source"`
+		// mbox 파일 또는 maildir 디렉터리 경로
+		Path string `yaml:"path"`
+		IMAP struct {
+			Host     string `yaml:"host"`
+			Port     int    `yaml:"port"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+			Mailbox  string `yaml:"mailbox"`
+			// true이면 TLS 없이 접속 (localhost 전용)
+			Plaintext bool `yaml:"plaintext"`
+		} `yaml:"imap"`
+		Interval time.Duration `yaml:"interval"`
+	} `yaml:"bounces"`
+}
+
+// SMTPAccountConfig is the connection of one SMTP account.
+type SMTPAccountConfig struct {
+	// smtp(기본값), http(메일 발송 서비스의 JSON API), file(디렉터리에 .eml/maildir로 저장), memory(메모리에만 보관, 테스트용)
+	Transport string `yaml:"transport"`
+	// transport가 http일 때 사용할 API 설정
+	HTTP struct {
+		Endpoint   string            `yaml:"endpoint"`
+		Method     string            `yaml:"method"`
+		AuthHeader string            `yaml:"auth_header"`
+		AuthValue  string            `yaml:"auth_value"`
+		Headers    map[string]string `yaml:"headers"`
+		Timeout    time.Duration     `yaml:"timeout"`
+		// 요청 JSON의 필드 이름, "content.html"처럼 점으로 중첩하고 "-"이면 보내지 않음
+		Fields struct {
+			From                  string `yaml:"from"`
+			To                    string `yaml:"to"`
+			Cc                    string `yaml:"cc"`
+			Bcc                   string `yaml:"bcc"`
+			ReplyTo               string `yaml:"reply_to"`
+			Subject               string `yaml:"subject"`
+			HTML                  string `yaml:"html"`
+			Text                  string `yaml:"text"`
+			Headers               string `yaml:"headers"`
+			Attachments           string `yaml:"attachments"`
+			AttachmentFilename    string `yaml:"attachment_filename"`
+			AttachmentContent     string `yaml:"attachment_content"`
+			AttachmentContentType string `yaml:"attachment_content_type"`
+			AttachmentContentID   string `yaml:"attachment_content_id"`
+		} `yaml:"fields"`
+	} `yaml:"http"`
+	// transport가 file일 때 메일을 저장할 위치
+	File struct {
+		Dir    string `yaml:"dir"`
+		Format string `yaml:"format"` // eml(기본값) 또는 maildir
+	} `yaml:"file"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	PoolSize int    `yaml:"pool_size"`
+	// 계정별 발송 속도 제한, 0이면 제한 없음
+	RateLimit struct {
+		PerSecond int `yaml:"per_second"`
+		PerMinute int `yaml:"per_minute"`
+		PerDay    int `yaml:"per_day"`
+	} `yaml:"rate_limit"`
+	// 일시적인 오류(4xx, 네트워크 오류)만 지수 백오프로 재시도
+	Retry struct {
+		MaxAttempts     int           `yaml:"max_attempts"`
+		InitialInterval time.Duration `yaml:"initial_interval"`
+		MaxInterval     time.Duration `yaml:"max_interval"`
+	} `yaml:"retry"`
+	// 모든 릴레이에 적용되는 TLS 설정
+	TLS TLSConfig `yaml:"tls"`
+	// 모든 릴레이에 적용되는 인증 방식
+	Auth AuthConfig `yaml:"auth"`
+	// domain을 설정하면 이 계정으로 보내는 모든 메일에 DKIM 서명을 추가
+	DKIM struct {
+		Domain   string `yaml:"domain"`
+		Selector string `yaml:"selector"`
+		KeyPath  string `yaml:"private_key_path"`
+	} `yaml:"dkim"`
+	// 장애 시 priority가 낮은 순서로 넘어가며 사용할 릴레이, 비어 있으면 host/port를 사용
+	Relays []struct {
+		Name     string `yaml:"name"`
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		Priority int    `yaml:"priority"`
+		PoolSize int    `yaml:"pool_size"`
+		// 비어 있으면 계정의 tls, auth 설정을 사용
+		TLS  TLSConfig  `yaml:"tls"`
+		Auth AuthConfig `yaml:"auth"`
+	} `yaml:"relays"`
+}
+
+// TLSConfig is the tls subsection of an SMTP account or relay.
+type TLSConfig struct {
+	// opportunistic(기본값, 465 포트는 implicit), starttls(필수), implicit, none
+	Mode       string `yaml:"mode"`
+	CAFile     string `yaml:"ca_file"`
+	ServerName string `yaml:"server_name"`
+}
+
+// AuthConfig is the auth subsection of an SMTP account or relay.
+type AuthConfig struct {
+	// plain(username이 있으면 기본값), login, cram-md5, xoauth2, none(username이 없으면 기본값)
+	Mechanism string `yaml:"mechanism"`
+	// xoauth2에서 refresh token으로 access token을 갱신할 때 사용
+	OAuth2 struct {
+		TokenURL     string `yaml:"token_url"`
+		ClientID     string `yaml:"client_id"`
+		ClientSecret string `yaml:"client_secret"`
+		RefreshToken string `yaml:"refresh_token"`
+	} `yaml:"oauth2"`
+}
+
+func (a AuthConfig) config() email.AuthConfig {
+	return email.AuthConfig{
+		Mechanism: email.AuthMechanism(a.Mechanism),
+		OAuth2: email.OAuth2Config{
+			TokenURL:     a.OAuth2.TokenURL,
+			ClientID:     a.OAuth2.ClientID,
+			ClientSecret: a.OAuth2.ClientSecret,
+			RefreshToken: a.OAuth2.RefreshToken,
+		},
+	}
+}
+
+func (t TLSConfig) config() email.TLSConfig {
+	return email.TLSConfig{
+		Mode:       email.TLSMode(t.Mode),
+		CAFile:     t.CAFile,
+		ServerName: t.ServerName,
+	}
+}
+
+// account creates the account with the configured transport.
+func (c SMTPAccountConfig) account(name string) (*email.Account, error) {
+	var sender email.Sender
+	switch c.Transport {
+	case "", "smtp":
+		client, err := c.client()
+		if err != nil {
+			return nil, err
+		}
+		sender = client
+	case "http":
+		f := c.HTTP.Fields
+		transport, err := email.NewHTTPTransport(email.HTTPConfig{
+			Endpoint:    c.HTTP.Endpoint,
+			Method:      c.HTTP.Method,
+			AuthHeader:  c.HTTP.AuthHeader,
+			AuthValue:   c.HTTP.AuthValue,
+			Headers:     c.HTTP.Headers,
+			From:        c.From,
+			Timeout:     c.HTTP.Timeout,
+			Concurrency: c.PoolSize,
+			Fields: email.HTTPFieldMapping{
+				From:                  f.From,
+				To:                    f.To,
+				Cc:                    f.Cc,
+				Bcc:                   f.Bcc,
+				ReplyTo:               f.ReplyTo,
+				Subject:               f.Subject,
+				HTML:                  f.HTML,
+				Text:                  f.Text,
+				Headers:               f.Headers,
+				Attachments:           f.Attachments,
+				AttachmentFilename:    f.AttachmentFilename,
+				AttachmentContent:     f.AttachmentContent,
+				AttachmentContentType: f.AttachmentContentType,
+				AttachmentContentID:   f.AttachmentContentID,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sender = transport
+	case "file":
+		transport, err := email.NewFileTransport(c.File.Dir, email.FileFormat(c.File.Format), c.From)
+		if err != nil {
+			return nil, err
+		}
+		if c.DKIM.Domain != "" {
+			if transport.DKIM, err = email.NewDKIMSigner(c.dkim()); err != nil {
+				return nil, fmt.Errorf("invalid DKIM settings: %w", err)
+			}
+		}
+		sender = transport
+	case "memory":
+		sender = email.NewMemoryTransport(c.From)
+	default:
+		return nil, fmt.Errorf("unknown transport %q", c.Transport)
+	}
+	return email.NewAccount(name, sender, email.RateLimit{
+		PerSecond: c.RateLimit.PerSecond,
+		PerMinute: c.RateLimit.PerMinute,
+		PerDay:    c.RateLimit.PerDay,
+	}, email.RetryPolicy{
+		MaxAttempts:     c.Retry.MaxAttempts,
+		InitialInterval: c.Retry.InitialInterval,
+		MaxInterval:     c.Retry.MaxInterval,
+	}), nil
+}
+
+func (c SMTPAccountConfig) dkim() email.DKIMConfig {
+	return email.DKIMConfig{
+		Domain:   c.DKIM.Domain,
+		Selector: c.DKIM.Selector,
+		KeyPath:  c.DKIM.KeyPath,
+	}
+}
+
+// client creates the SMTP client of the account.
+func (c SMTPAccountConfig) client() (*email.SMTPClient, error) {
+	relays := make([]email.RelayConfig, 0, len(c.Relays))
+	for _, r := range c.Relays {
+		relays = append(relays, email.RelayConfig{
+			Name:     r.Name,
+			Host:     r.Host,
+			Port:     r.Port,
+			Username: r.Username,
+			Password: r.Password,
+			Priority: r.Priority,
+			PoolSize: r.PoolSize,
+			TLS:      r.TLS.config(),
+			Auth:     r.Auth.config(),
+		})
+	}
+	return email.NewSMTPClient(email.SMTPConfig{
+		Host:     c.Host,
+		Port:     c.Port,
+		Username: c.Username,
+		Password: c.Password,
+		From:     c.From,
+		PoolSize: c.PoolSize,
+		TLS:      c.TLS.config(),
+		Auth:     c.Auth.config(),
+		DKIM:     c.dkim(),
+		Relays:   relays,
+	})
 }
 
 func loadConfig(path string) (*Config, error) {
@@ -53,6 +331,15 @@ func loadConfig(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if cfg.Jobs.Dir == "" {
+		cfg.Jobs.Dir = "./data/jobs"
+	}
+	if cfg.Suppression.File == "" {
+		cfg.Suppression.File = "./data/suppression.json"
+	}
+	if cfg.Jobs.IdempotencyWindow == 0 {
+		cfg.Jobs.IdempotencyWindow = 24 * time.Hour
+	}
 	return &cfg, nil
 }
 
@@ -91,13 +378,38 @@ func main() {
 	}
 	log.Println("Authentik client initialized.")
 
-	smtpClient := email.NewSMTPClient(email.SMTPConfig{
-		Host:     cfg.SMTP.Host,
-		Port:     cfg.SMTP.Port,
-		Username: cfg.SMTP.Username,
-		Password: cfg.SMTP.Password,
-		From:     cfg.SMTP.From,
-	})
+	defaultAccount, err := cfg.SMTP.account(email.DefaultAccount)
+	if err != nil {
+		log.Fatalf("SMTP 설정 오류: %v", err)
+	}
+	accounts := map[string]*email.Account{email.DefaultAccount: defaultAccount}
+	for _, accountCfg := range cfg.SMTP.Accounts {
+		if accountCfg.Name == "" || accounts[accountCfg.Name] != nil {
+			log.Fatalf("SMTP 계정 이름이 비어 있거나 중복되었습니다: %q", accountCfg.Name)
+		}
+		account, err := accountCfg.account(accountCfg.Name)
+		if err != nil {
+			log.Fatalf("SMTP 계정 %s 설정 오류: %v", accountCfg.Name, err)
+		}
+		accounts[accountCfg.Name] = account
+	}
+	for name, account := range accounts {
+		// 재시작 후에도 하루 발송 한도가 유지되도록 계정별 발송 시각을 기록합니다.
+		if err := account.Throttle.Persist(filepath.Join(cfg.Jobs.Dir, "throttle", name+".log")); err != nil {
+			log.Fatalf("SMTP 계정 %s의 발송 기록을 불러오지 못하였습니다: %v", name, err)
+		}
+	}
+	for name, account := range accounts {
+		client, ok := account.Sender.(*email.SMTPClient)
+		if !ok {
+			log.Printf("SMTP 계정 %s는 실제로 발송하지 않는 %T 전송 방식을 사용합니다.", name, account.Sender)
+			continue
+		}
+		// 설정한 TLS 모드를 만족하지 못하거나 인증을 거부하는 서버로는 발송하지 않도록 시작 시점에 확인합니다.
+		if err := client.CheckRelays(); err != nil {
+			log.Fatalf("SMTP 계정 %s의 TLS 또는 인증 설정을 만족하지 못합니다: %v", name, err)
+		}
+	}
 	log.Println("SMTP client initialized.")
 
 	tmplManager := email.NewTemplateManager(cfg.Templates.Email, cfg.Templates.Image)
@@ -106,7 +418,8 @@ func main() {
 		log.Fatalf("템플릿 디렉터리 읽기 실패: %v", err)
 	}
 	for _, file := range files {
-		if file.IsDir() {
+		// .txt 파일은 같은 이름의 .html 템플릿과 함께 텍스트 본문으로 로드됩니다.
+		if file.IsDir() || filepath.Ext(file.Name()) == ".txt" {
 			continue
 		}
 		if err := tmplManager.LoadTemplate(strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())), file.Name()); err != nil {
@@ -114,7 +427,79 @@ func main() {
 		}
 	}
 
-	apiHandler := web.NewAPIHandler(oidcSvc, tmplManager, smtpClient, authClient, cfg.Templates.Image)
+	jobStore, err := job.NewStore(cfg.Jobs.Dir)
+	if err != nil {
+		log.Fatalf("발송 작업 저장소 초기화 실패: %v", err)
+	}
+	jobManager := job.NewManager(jobStore, tmplManager, defaultAccount, cfg.Jobs.Workers)
+	jobManager.IdempotencyWindow = cfg.Jobs.IdempotencyWindow
+	jobManager.ArchiveMessages = cfg.Jobs.ArchiveMessages
+	if cfg.Archive.Dir != "" {
+		archiveStore, err := archive.NewStore(cfg.Archive.Dir)
+		if err != nil {
+			log.Fatalf("발송 기록 보관소 초기화 실패: %v", err)
+		}
+		jobManager.Archive = archiveStore
+	}
+	suppressions, err := suppression.NewList(cfg.Suppression.File)
+	if err != nil {
+		log.Fatalf("발송 제외 목록 초기화 실패: %v", err)
+	}
+	jobManager.Suppressions = suppressions
+	jobManager.Accounts = accounts
+	for _, sender := range cfg.Senders {
+		identity := email.Identity{
+			Name:        sender.Name,
+			DisplayName: sender.DisplayName,
+			Address:     sender.Address,
+			Account:     sender.Account,
+			Users:       sender.Users,
+			Groups:      sender.Groups,
+		}
+		if identity.Account == "" {
+			identity.Account = email.DefaultAccount
+		}
+		if err := identity.Validate(accounts); err != nil {
+			log.Fatalf("발신자 설정 오류: %v", err)
+		}
+		if _, ok := jobManager.Identities[identity.Name]; ok {
+			log.Fatalf("발신자 이름이 중복되었습니다: %s", identity.Name)
+		}
+		jobManager.Identities[identity.Name] = identity
+	}
+	jobManager.Resume()
+	log.Println("Job manager initialized.")
+
+	if cfg.Bounces.Source != "" {
+		var source bounce.Source
+		switch cfg.Bounces.Source {
+		case "imap":
+			i := cfg.Bounces.IMAP
+			source = &bounce.IMAPSource{Config: bounce.IMAPConfig{
+				Host:      i.Host,
+				Port:      i.Port,
+				Username:  i.Username,
+				Password:  i.Password,
+				Mailbox:   i.Mailbox,
+				Plaintext: i.Plaintext,
+			}}
+		case "mbox":
+			source = &bounce.MboxSource{
+				Path:      cfg.Bounces.Path,
+				StatePath: filepath.Join(cfg.Jobs.Dir, "bounces", "mbox.offset"),
+			}
+		case "maildir":
+			source = &bounce.MaildirSource{Dir: cfg.Bounces.Path}
+		default:
+			log.Fatalf("알 수 없는 반송 메일함 종류입니다: %s", cfg.Bounces.Source)
+		}
+		processor := &bounce.Processor{Source: source, Manager: jobManager, Interval: cfg.Bounces.Interval}
+		go processor.Run(context.Background())
+		log.Printf("반송 메일 처리 시작 (%s)", cfg.Bounces.Source)
+	}
+
+	apiHandler := web.NewAPIHandler(oidcSvc, tmplManager, defaultAccount.Sender, authClient, jobManager, cfg.Templates.Image)
+	apiHandler.Admins = web.AccessList{Users: cfg.Admins.Users, Groups: cfg.Admins.Groups}
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/login", oidcSvc.LoginHandler)
@@ -125,6 +510,17 @@ func main() {
 	mux.Handle("/api/templates/preview/", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.PreviewTemplateHandler)))
 	mux.Handle("/api/users", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.UsersHandler)))
 	mux.Handle("/api/email", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.EmailHandler)))
+	mux.Handle("/api/email/test", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.EmailTestHandler)))
+	mux.Handle("/api/email/eml", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.EmailEMLHandler)))
+	mux.Handle("/api/email/jobs", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.JobsListHandler)))
+	mux.Handle("/api/email/jobs/", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.JobHandler)))
+	mux.Handle("/api/archive", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.ArchiveHandler)))
+	mux.Handle("/api/archive/", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.ArchiveEntryHandler)))
+	mux.Handle("/api/suppressions", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.SuppressionsHandler)))
+	mux.Handle("/api/suppressions/", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.SuppressionHandler)))
+	mux.Handle("/api/senders", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.SendersHandler)))
+	mux.Handle("/api/admin/smtp/health", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.SMTPHealthHandler)))
+	mux.Handle("/api/admin/dkim", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.DKIMHandler)))
 	mux.Handle("/api/me", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.MeHandler)))
 	mux.Handle("/api/images/upload", oidcSvc.AuthMiddleware(http.HandlerFunc(apiHandler.ImageUploadHandler)))
 	mux.Handle("/api/images/", oidcSvc.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
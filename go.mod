@@ -9,6 +9,7 @@ require (
 	github.com/vanng822/go-premailer v1.23.0
 	goauthentik.io/api/v3 v3.2024123.4
 	golang.org/x/crypto v0.33.0
+	golang.org/x/net v0.34.0
 	golang.org/x/oauth2 v0.26.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -20,6 +21,5 @@ require (
 	github.com/gorilla/css v1.0.1 // indirect
 	github.com/gorilla/securecookie v1.1.2 // indirect
 	github.com/vanng822/css v1.0.1 // indirect
-	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
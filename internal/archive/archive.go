@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when no archived message has the requested ID.
+var ErrNotFound = errors.New("archived message not found")
+
+// Entry is the record of one sent message.
+type Entry struct {
+	ID string `json:"id"`
+	// JobID is empty for test sends, which do not belong to a job.
+	JobID           string `json:"job_id,omitempty"`
+	Recipient       string `json:"recipient"`
+	Subject         string `json:"subject"`
+	Template        string `json:"template"`
+	TemplateVersion string `json:"template_version,omitempty"`
+	// HTML is the rendered body. It is left out of search results.
+	HTML string `json:"html,omitempty"`
+	// Requester is the user who requested the send.
+	Requester string `json:"requester"`
+	// Sender is the name of the sender identity, empty for the default From.
+	Sender    string `json:"sender,omitempty"`
+	From      string `json:"from"`
+	MessageID string `json:"message_id,omitempty"`
+	// Response is the server's reply to the message, such as the final SMTP response.
+	Response string    `json:"response,omitempty"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// Query selects archived messages. Empty fields match everything.
+type Query struct {
+	// Recipient matches the recipient address exactly, ignoring case.
+	Recipient string
+	// Since and Until bound the send time; Until is exclusive.
+	Since, Until time.Time
+	Template     string
+	// Subject matches any subject containing it, ignoring case.
+	Subject string
+	Limit   int
+	Offset  int
+}
+
+func (q Query) matches(e *Entry) bool {
+	if q.Recipient != "" && !strings.EqualFold(e.Recipient, q.Recipient) {
+		return false
+	}
+	if !q.Since.IsZero() && e.SentAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.SentAt.Before(q.Until) {
+		return false
+	}
+	if q.Template != "" && e.Template != q.Template {
+		return false
+	}
+	if q.Subject != "" && !strings.Contains(strings.ToLower(e.Subject), strings.ToLower(q.Subject)) {
+		return false
+	}
+	return true
+}
+
+// record locates an entry's line in the archive file. Only the metadata is kept in memory;
+// the rendered HTML is read from the file when the entry is requested.
+type record struct {
+	entry  Entry
+	offset int64
+	length int
+}
+
+// Store appends entries as JSON lines to a single file and indexes them in memory.
+type Store struct {
+	path    string
+	file    *os.File
+	size    int64
+	records []*record
+	byID    map[string]*record
+	mu      sync.RWMutex
+}
+
+// NewStore opens the archive in dir, creating it if needed, and indexes the stored entries.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, "sent.jsonl")
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	s := &Store{path: path, file: file, byID: make(map[string]*record)}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load indexes the archive file. A truncated last line, left by a crash during Add, is cut off.
+func (s *Store) load() error {
+	r := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				if err := s.file.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to repair archive %s: %w", s.path, err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", s.path, err)
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("failed to unmarshal archive entry at offset %d: %w", offset, err)
+		}
+		s.index(&e, offset, len(line))
+		offset += int64(len(line))
+	}
+	s.size = offset
+	return nil
+}
+
+func (s *Store) index(e *Entry, offset int64, length int) {
+	rec := &record{entry: *e, offset: offset, length: length}
+	rec.entry.HTML = ""
+	s.records = append(s.records, rec)
+	s.byID[e.ID] = rec
+}
+
+// Add assigns the entry an ID, unless it has one, and appends it to the archive.
+func (s *Store) Add(e *Entry) error {
+	if e.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+	}
+	if e.SentAt.IsZero() {
+		e.SentAt = time.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.byID[e.ID]; exists {
+		return fmt.Errorf("archive entry %s already exists", e.ID)
+	}
+	if _, err := s.file.WriteAt(data, s.size); err != nil {
+		return fmt.Errorf("failed to write archive entry: %w", err)
+	}
+	s.index(e, s.size, len(data))
+	s.size += int64(len(data))
+	return nil
+}
+
+// Get returns the entry with the given ID, including its rendered HTML.
+func (s *Store) Get(id string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	data := make([]byte, rec.length)
+	if _, err := s.file.ReadAt(data, rec.offset); err != nil {
+		return nil, fmt.Errorf("failed to read archive entry %s: %w", id, err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal archive entry %s: %w", id, err)
+	}
+	return &e, nil
+}
+
+// Search returns the entries matching the query, newest first and without their HTML,
+// together with the number of matches before Limit and Offset are applied.
+func (s *Store) Search(q Query) ([]Entry, int) {
+	s.mu.RLock()
+	var matches []Entry
+	for _, rec := range s.records {
+		if q.matches(&rec.entry) {
+			matches = append(matches, rec.entry)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].SentAt.After(matches[b].SentAt)
+	})
+	total := len(matches)
+	if q.Offset > 0 {
+		if q.Offset >= len(matches) {
+			return []Entry{}, total
+		}
+		matches = matches[q.Offset:]
+	}
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	if matches == nil {
+		matches = []Entry{}
+	}
+	return matches, total
+}
+
+// Close closes the archive file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -151,6 +151,8 @@ func (s *OIDCService) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 		Email         string `json:"email"`
 		EmailVerified bool   `json:"email_verified"`
 		Name          string `json:"name"`
+		// Authentik puts the user's group names in the groups claim (profile scope).
+		Groups []string `json:"groups"`
 	}
 	if err := idToken.Claims(&claims); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to parse claims: %v", err), http.StatusInternalServerError)
@@ -160,6 +162,7 @@ func (s *OIDCService) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	session.Values["id_token"] = rawIDToken
 	session.Values["email"] = claims.Email
 	session.Values["name"] = claims.Name
+	session.Values["groups"] = claims.Groups
 	delete(session.Values, "state")
 	if err := session.Save(r, w); err != nil {
 		http.Error(w, "Failed to save session", http.StatusInternalServerError)
@@ -0,0 +1,156 @@
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// ErrNotDSN is returned by Parse for messages that are not delivery status notifications.
+var ErrNotDSN = errors.New("message is not a delivery status notification")
+
+// Report is a parsed delivery status notification (RFC 3464).
+type Report struct {
+	// MessageID is the Message-Id of the original message, without angle brackets. It is
+	// empty when the report includes neither the original message nor its headers.
+	MessageID    string
+	ReportingMTA string
+	Recipients   []Recipient
+}
+
+// Recipient holds the per-recipient fields of a report.
+type Recipient struct {
+	FinalRecipient    string
+	OriginalRecipient string
+	// Action is one of failed, delayed, delivered, relayed or expanded.
+	Action string
+	// Status is the enhanced status code, such as 5.1.1.
+	Status     string
+	Diagnostic string
+}
+
+// Address returns the address the original message was sent to, preferring the
+// Original-Recipient field since Final-Recipient may be the target of a forward.
+func (r Recipient) Address() string {
+	if r.OriginalRecipient != "" {
+		return r.OriginalRecipient
+	}
+	return r.FinalRecipient
+}
+
+// Failed reports whether delivery to the recipient failed permanently.
+func (r Recipient) Failed() bool {
+	return strings.EqualFold(r.Action, "failed")
+}
+
+// Detail describes the failure for logs and the suppression list.
+func (r Recipient) Detail() string {
+	if r.Diagnostic != "" {
+		return r.Status + " " + r.Diagnostic
+	}
+	return r.Status
+}
+
+// Parse reads a multipart/report message with report-type delivery-status.
+func Parse(data []byte) (*Report, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "delivery-status") {
+		return nil, ErrNotDSN
+	}
+	report := &Report{}
+	found := false
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report part: %w", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body := decodePart(part)
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status":
+			if err := report.parseStatus(body); err != nil {
+				return nil, err
+			}
+			found = true
+		case "message/rfc822", "text/rfc822-headers", "message/global", "message/global-headers":
+			header, err := textproto.NewReader(bufio.NewReader(body)).ReadMIMEHeader()
+			if err != nil && len(header) == 0 {
+				continue
+			}
+			report.MessageID = strings.Trim(strings.TrimSpace(header.Get("Message-Id")), "<>")
+		}
+	}
+	if !found {
+		return nil, ErrNotDSN
+	}
+	return report, nil
+}
+
+// decodePart undoes a base64 or quoted-printable transfer encoding that multipart.Reader
+// left in place.
+func decodePart(part *multipart.Part) io.Reader {
+	switch strings.ToLower(part.Header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, part)
+	case "quoted-printable":
+		return quotedprintable.NewReader(part)
+	}
+	return part
+}
+
+// parseStatus reads the per-message field group followed by one group per recipient.
+func (r *Report) parseStatus(body io.Reader) error {
+	tp := textproto.NewReader(bufio.NewReader(body))
+	perMessage, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to parse delivery status: %w", err)
+	}
+	r.ReportingMTA = fieldValue(perMessage.Get("Reporting-Mta"))
+	for err != io.EOF {
+		var fields textproto.MIMEHeader
+		fields, err = tp.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to parse delivery status: %w", err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		r.Recipients = append(r.Recipients, Recipient{
+			FinalRecipient:    addressValue(fields.Get("Final-Recipient")),
+			OriginalRecipient: addressValue(fields.Get("Original-Recipient")),
+			Action:            strings.ToLower(strings.TrimSpace(fields.Get("Action"))),
+			Status:            strings.TrimSpace(fields.Get("Status")),
+			Diagnostic:        fieldValue(fields.Get("Diagnostic-Code")),
+		})
+	}
+	return nil
+}
+
+// fieldValue strips the type prefix of a typed field, as in "rfc822; user@example.com".
+func fieldValue(v string) string {
+	if _, value, ok := strings.Cut(v, ";"); ok {
+		v = value
+	}
+	return strings.TrimSpace(v)
+}
+
+func addressValue(v string) string {
+	return strings.Trim(fieldValue(v), "<>")
+}
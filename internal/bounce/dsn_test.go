@@ -0,0 +1,109 @@
+package bounce
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file string
+		want *Report
+		err  error
+	}{
+		{
+			file: "postfix.eml",
+			want: &Report{
+				MessageID:    "1772499601.123456.1@mail.example.com",
+				ReportingMTA: "mail.example.com",
+				Recipients: []Recipient{{
+					FinalRecipient:    "nobody@example.net",
+					OriginalRecipient: "nobody@example.net",
+					Action:            "failed",
+					Status:            "5.1.1",
+					Diagnostic:        "550 5.1.1 <nobody@example.net>: Recipient address rejected: User unknown",
+				}},
+			},
+		},
+		{
+			// Base64 encoded parts, several recipients and only the original headers.
+			file: "exchange-base64.eml",
+			want: &Report{
+				MessageID:    "1772499602.654321.7@mail.example.com",
+				ReportingMTA: "EX01.corp.example.org",
+				Recipients: []Recipient{
+					{
+						FinalRecipient: "alice@corp.example.org",
+						Action:         "failed",
+						Status:         "5.2.2",
+						Diagnostic:     "554 5.2.2 mailbox full;STOREDRV.Deliver.Exception:QuotaExceededException",
+					},
+					{
+						FinalRecipient: "bob@corp.example.org",
+						Action:         "delayed",
+						Status:         "4.4.7",
+						Diagnostic:     "451 4.4.7 Message delayed",
+					},
+					{
+						FinalRecipient:    "team-lead@corp.example.org",
+						OriginalRecipient: "carol@corp.example.org",
+						Action:            "failed",
+						Status:            "5.1.10",
+					},
+				},
+			},
+		},
+		{
+			file: "delayed-no-original.eml",
+			want: &Report{
+				ReportingMTA: "relay.example.com",
+				Recipients: []Recipient{{
+					FinalRecipient: "slow@example.net",
+					Action:         "delayed",
+					Status:         "4.4.1",
+					Diagnostic:     "421 4.4.1 Connection timed out",
+				}},
+			},
+		},
+		{file: "read-receipt.eml", err: ErrNotDSN},
+		{file: "reply.eml", err: ErrNotDSN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(data)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecipientAddress(t *testing.T) {
+	forwarded := Recipient{FinalRecipient: "team-lead@corp.example.org", OriginalRecipient: "carol@corp.example.org", Action: "failed", Status: "5.1.10"}
+	if got := forwarded.Address(); got != "carol@corp.example.org" {
+		t.Errorf("Address() = %q, want the original recipient", got)
+	}
+	if !forwarded.Failed() || forwarded.Detail() != "5.1.10" {
+		t.Errorf("Failed() = %v, Detail() = %q", forwarded.Failed(), forwarded.Detail())
+	}
+	delayed := Recipient{FinalRecipient: "bob@corp.example.org", Action: "delayed", Status: "4.4.7"}
+	if delayed.Failed() {
+		t.Error("a delayed recipient is reported as failed")
+	}
+}
@@ -0,0 +1,243 @@
+package bounce
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IMAPConfig is the mailbox an IMAPSource reads.
+type IMAPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Mailbox defaults to INBOX.
+	Mailbox string
+	// Plaintext disables TLS, which is only allowed for a server on localhost so that the
+	// password is never sent in the clear. Otherwise the connection uses implicit TLS, as
+	// on port 993.
+	Plaintext bool
+}
+
+// IMAPSource reads the unseen messages of an IMAP mailbox. Handled messages are flagged
+// as seen; other messages are left unread and skipped until the next restart.
+type IMAPSource struct {
+	Config IMAPConfig
+
+	mu      sync.Mutex
+	skipped map[string]bool
+}
+
+const imapTimeout = time.Minute
+
+// maxIMAPLiteral bounds the size of a message the server may send, so that a bad length
+// cannot make the client allocate arbitrary memory.
+const maxIMAPLiteral = 64 << 20
+
+func (s *IMAPSource) Poll(fn Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skipped == nil {
+		s.skipped = make(map[string]bool)
+	}
+	c, err := dialIMAP(s.Config)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	mailbox := s.Config.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	lines, err := c.cmd("SELECT " + imapQuote(mailbox))
+	if err != nil {
+		return fmt.Errorf("failed to select mailbox %s: %w", mailbox, err)
+	}
+	// UIDs are only stable together with the mailbox's UIDVALIDITY.
+	validity := ""
+	for _, line := range lines {
+		if _, rest, ok := strings.Cut(line.text, "[UIDVALIDITY "); ok {
+			validity, _, _ = strings.Cut(rest, "]")
+		}
+	}
+
+	lines, err = c.cmd("UID SEARCH UNSEEN")
+	if err != nil {
+		return fmt.Errorf("failed to search mailbox %s: %w", mailbox, err)
+	}
+	var uids []string
+	for _, line := range lines {
+		if rest, ok := strings.CutPrefix(line.text, "* SEARCH"); ok {
+			uids = append(uids, strings.Fields(rest)...)
+		}
+	}
+	for _, uid := range uids {
+		key := validity + "/" + uid
+		if s.skipped[key] {
+			continue
+		}
+		lines, err := c.cmd("UID FETCH " + uid + " BODY.PEEK[]")
+		if err != nil {
+			return fmt.Errorf("failed to fetch message %s: %w", uid, err)
+		}
+		var data []byte
+		for _, line := range lines {
+			if len(line.literals) > 0 {
+				data = line.literals[0]
+				break
+			}
+		}
+		if data == nil {
+			continue
+		}
+		handled, err := fn(data)
+		if err != nil {
+			continue
+		}
+		if !handled {
+			s.skipped[key] = true
+			continue
+		}
+		if _, err := c.cmd("UID STORE " + uid + ` +FLAGS.SILENT (\Seen)`); err != nil {
+			return fmt.Errorf("failed to mark message %s as seen: %w", uid, err)
+		}
+	}
+	return nil
+}
+
+// imapConn is a minimal IMAP4rev1 client (RFC 3501) supporting the few commands the
+// bounce processor needs.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapLine is one response line with the literals it contained, in order.
+type imapLine struct {
+	text     string
+	literals [][]byte
+}
+
+func dialIMAP(cfg IMAPConfig) (*imapConn, error) {
+	if cfg.Plaintext && !isLocalhost(cfg.Host) {
+		return nil, fmt.Errorf("plaintext IMAP is only allowed to localhost, not %s", cfg.Host)
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 993
+		if cfg.Plaintext {
+			port = 143
+		}
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	var nc net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if cfg.Plaintext {
+		nc, err = dialer.Dial("tcp", addr)
+	} else {
+		nc, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: cfg.Host})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server %s: %w", addr, err)
+	}
+	c := &imapConn{conn: nc, r: bufio.NewReader(nc)}
+	nc.SetDeadline(time.Now().Add(imapTimeout))
+	greeting, err := c.readLine()
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to read IMAP greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		nc.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting.text)
+	}
+	if strings.HasPrefix(greeting.text, "* OK") {
+		if _, err := c.cmd("LOGIN " + imapQuote(cfg.Username) + " " + imapQuote(cfg.Password)); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("IMAP login failed: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// cmd sends a command and returns the untagged response lines, or an error if the
+// server did not answer OK.
+func (c *imapConn) cmd(command string) ([]imapLine, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, err
+	}
+	var lines []imapLine
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(line.text, tag+" "); ok {
+			if strings.HasPrefix(rest, "OK") {
+				return lines, nil
+			}
+			return nil, errors.New(rest)
+		}
+		lines = append(lines, line)
+	}
+}
+
+// readLine reads a response line, including any literals ("{n}" followed by n bytes) it contains.
+func (c *imapConn) readLine() (imapLine, error) {
+	var line imapLine
+	for {
+		text, err := c.r.ReadString('\n')
+		if err != nil {
+			return line, err
+		}
+		text = strings.TrimRight(text, "\r\n")
+		line.text += text
+		if !strings.HasSuffix(text, "}") {
+			return line, nil
+		}
+		open := strings.LastIndexByte(text, '{')
+		if open < 0 {
+			return line, nil
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(text[open+1:len(text)-1], "+"))
+		if err != nil {
+			return line, nil
+		}
+		if n < 0 || n > maxIMAPLiteral {
+			return line, fmt.Errorf("IMAP literal of %d bytes exceeds the limit of %d bytes", n, maxIMAPLiteral)
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return line, err
+		}
+		line.literals = append(line.literals, literal)
+	}
+}
+
+func (c *imapConn) close() {
+	c.cmd("LOGOUT")
+	c.conn.Close()
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// imapQuote returns s as an IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,180 @@
+package bounce
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeIMAP is a scripted IMAP server holding a few unseen messages.
+type fakeIMAP struct {
+	t        *testing.T
+	ln       net.Listener
+	messages map[string][]byte
+	// literal, if set, is announced instead of the real size of fetched messages.
+	literal string
+
+	mu       sync.Mutex
+	commands []string
+	seen     []string
+}
+
+func newFakeIMAP(t *testing.T, messages map[string][]byte) *fakeIMAP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeIMAP{t: t, ln: ln, messages: messages}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeIMAP) config() IMAPConfig {
+	port := s.ln.Addr().(*net.TCPAddr).Port
+	return IMAPConfig{Host: "127.0.0.1", Port: port, Username: "bounces", Password: `p"w\d`, Plaintext: true}
+}
+
+func (s *fakeIMAP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeIMAP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+		switch {
+		case strings.HasPrefix(command, "LOGIN "):
+			if command != `LOGIN "bounces" "p\"w\\d"` {
+				fmt.Fprintf(conn, "%s NO [AUTHENTICATIONFAILED] invalid credentials\r\n", tag)
+				continue
+			}
+		case strings.HasPrefix(command, "SELECT "):
+			fmt.Fprint(conn, "* 2 EXISTS\r\n* OK [UIDVALIDITY 42] UIDs valid\r\n")
+		case command == "UID SEARCH UNSEEN":
+			var uids []string
+			for uid := range s.messages {
+				uids = append(uids, uid)
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(command, "UID FETCH "):
+			uid := strings.Fields(command)[2]
+			data := s.messages[uid]
+			size := strconv.Itoa(len(data))
+			if s.literal != "" {
+				size = s.literal
+			}
+			fmt.Fprintf(conn, "* 1 FETCH (UID %s BODY[] {%s}\r\n%s)\r\n", uid, size, data)
+		case strings.HasPrefix(command, "UID STORE "):
+			s.mu.Lock()
+			s.seen = append(s.seen, strings.Fields(command)[2])
+			s.mu.Unlock()
+		case command == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE logging out\r\n%s OK LOGOUT completed\r\n", tag)
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func (s *fakeIMAP) fetched() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, c := range s.commands {
+		if strings.HasPrefix(c, "UID FETCH ") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestIMAPSourcePoll(t *testing.T) {
+	bounce, err := os.ReadFile(filepath.Join("testdata", "postfix.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := os.ReadFile(filepath.Join("testdata", "reply.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newFakeIMAP(t, map[string][]byte{"7": bounce, "8": reply})
+	s := &IMAPSource{Config: server.config()}
+
+	var handled [][]byte
+	fn := func(data []byte) (bool, error) {
+		if _, err := Parse(data); err != nil {
+			return false, nil
+		}
+		handled = append(handled, data)
+		return true, nil
+	}
+	if err := s.Poll(fn); err != nil {
+		t.Fatal(err)
+	}
+	if len(handled) != 1 || string(handled[0]) != string(bounce) {
+		t.Fatalf("handled %d messages, want the bounce byte for byte", len(handled))
+	}
+	server.mu.Lock()
+	seen := strings.Join(server.seen, " ")
+	server.mu.Unlock()
+	if seen != "7" {
+		t.Errorf("flagged %q as seen, want only the bounce", seen)
+	}
+
+	// The reply stays unread and is not fetched again.
+	if err := s.Poll(fn); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.fetched(); n != 3 {
+		t.Errorf("fetched %d times, want the reply skipped on the second poll", n)
+	}
+}
+
+func TestIMAPSourceLoginFailure(t *testing.T) {
+	server := newFakeIMAP(t, nil)
+	cfg := server.config()
+	cfg.Password = "wrong"
+	err := (&IMAPSource{Config: cfg}).Poll(func([]byte) (bool, error) { return true, nil })
+	if err == nil || !strings.Contains(err.Error(), "AUTHENTICATIONFAILED") {
+		t.Errorf("err = %v, want the login failure", err)
+	}
+}
+
+func TestIMAPSourceRejectsLargeLiteral(t *testing.T) {
+	server := newFakeIMAP(t, map[string][]byte{"7": []byte("x")})
+	server.literal = strconv.Itoa(maxIMAPLiteral + 1)
+	err := (&IMAPSource{Config: server.config()}).Poll(func([]byte) (bool, error) { return true, nil })
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("err = %v, want the literal to be refused", err)
+	}
+}
+
+func TestIMAPPlaintextOnlyToLocalhost(t *testing.T) {
+	_, err := dialIMAP(IMAPConfig{Host: "imap.example.com", Plaintext: true})
+	if err == nil || !strings.Contains(err.Error(), "only allowed to localhost") {
+		t.Errorf("err = %v, want plaintext to a remote host to be refused", err)
+	}
+}
@@ -0,0 +1,71 @@
+package bounce
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"mail-manager/internal/job"
+)
+
+// Processor polls a Source for delivery status notifications and records the failed
+// recipients with the job manager.
+type Processor struct {
+	Source  Source
+	Manager *job.Manager
+	// Interval is the time between polls. Defaults to five minutes.
+	Interval time.Duration
+}
+
+// Run polls the source until the context is cancelled.
+func (p *Processor) Run(ctx context.Context) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Source.Poll(p.handle); err != nil {
+			log.Printf("반송 메일함 읽기 실패: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handle processes one message. Reports about mail this server did not send are handled
+// too, so they are not read again, but change nothing.
+func (p *Processor) handle(data []byte) (bool, error) {
+	report, err := Parse(data)
+	if errors.Is(err, ErrNotDSN) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("반송 메일 해석 실패: %v", err)
+		return false, nil
+	}
+	if report.MessageID == "" {
+		log.Printf("원본 Message-ID가 없는 반송 메일을 건너뜁니다 (%s)", report.ReportingMTA)
+		return true, nil
+	}
+	for _, rec := range report.Recipients {
+		if !rec.Failed() {
+			continue
+		}
+		err := p.Manager.MarkBounced(report.MessageID, rec.Address(), rec.Detail())
+		if errors.Is(err, job.ErrNotFound) {
+			log.Printf("반송된 메일의 발송 기록을 찾을 수 없습니다 (%s, %s)", report.MessageID, rec.Address())
+			continue
+		}
+		if err != nil {
+			log.Printf("반송 처리 실패 (%s, %s): %v", report.MessageID, rec.Address(), err)
+			return false, err
+		}
+	}
+	return true, nil
+}
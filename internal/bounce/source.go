@@ -0,0 +1,209 @@
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Handler processes one message of a mailbox. It returns false for messages that are not
+// bounces, which sources leave untouched so that mail meant for people stays unread.
+type Handler func(data []byte) (handled bool, err error)
+
+// Source is a mailbox that bounces are read from.
+type Source interface {
+	// Poll calls fn for every message not processed before. Messages fn handled without an
+	// error are marked processed; failed ones are offered again on the next poll.
+	Poll(fn Handler) error
+}
+
+// MaildirSource reads the new messages of a maildir. Handled messages are moved to cur/
+// with the Seen flag, as a mail client would.
+type MaildirSource struct {
+	Dir string
+
+	mu      sync.Mutex
+	skipped map[string]bool
+}
+
+func (s *MaildirSource) Poll(fn Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skipped == nil {
+		s.skipped = make(map[string]bool)
+	}
+	files, err := os.ReadDir(filepath.Join(s.Dir, "new"))
+	if err != nil {
+		return fmt.Errorf("failed to read maildir %s: %w", s.Dir, err)
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") || s.skipped[name] {
+			continue
+		}
+		path := filepath.Join(s.Dir, "new", name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read message %s: %w", name, err)
+		}
+		handled, err := fn(data)
+		if err != nil {
+			continue
+		}
+		if !handled {
+			s.skipped[name] = true
+			continue
+		}
+		if err := os.Rename(path, filepath.Join(s.Dir, "cur", name+":2,S")); err != nil {
+			return fmt.Errorf("failed to mark message %s as read: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// MboxSource reads an mbox file. The file is never modified: each poll reads the messages
+// appended since the last one.
+type MboxSource struct {
+	Path string
+	// StatePath, if set, stores how far the file has been read, so that a restart does not
+	// process the old bounces again. Without it the whole file is read after a restart.
+	StatePath string
+
+	mu     sync.Mutex
+	loaded bool
+	offset int64
+}
+
+func (s *MboxSource) Poll(fn Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded {
+		if err := s.loadOffset(); err != nil {
+			return err
+		}
+		s.loaded = true
+	}
+	before := s.offset
+	err := s.read(fn)
+	if s.offset != before {
+		if saveErr := s.saveOffset(); err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+func (s *MboxSource) loadOffset() error {
+	if s.StatePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read mbox state %s: %w", s.StatePath, err)
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid mbox state %s: %w", s.StatePath, err)
+	}
+	s.offset = offset
+	return nil
+}
+
+// saveOffset atomically replaces the state file with the current offset.
+func (s *MboxSource) saveOffset() error {
+	if s.StatePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.StatePath), 0755); err != nil {
+		return fmt.Errorf("failed to create mbox state directory: %w", err)
+	}
+	tmp := s.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(s.offset, 10)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save mbox state: %w", err)
+	}
+	if err := os.Rename(tmp, s.StatePath); err != nil {
+		return fmt.Errorf("failed to save mbox state: %w", err)
+	}
+	return nil
+}
+
+// read processes the messages after s.offset and advances it past every message handled.
+func (s *MboxSource) read(fn Handler) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox %s: %w", s.Path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < s.offset {
+		// The file was truncated or replaced, e.g. by a mail client expunging it.
+		s.offset = 0
+	}
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	offset := s.offset
+	// start is the offset of the current message, or -1 before the first "From " line.
+	start := int64(-1)
+	var msg bytes.Buffer
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// The last message is complete once it ends with the blank line that separates
+			// messages; otherwise it is still being written and is read again next time.
+			if start >= 0 && len(line) == 0 && (bytes.HasSuffix(msg.Bytes(), []byte("\n\n")) || bytes.HasSuffix(msg.Bytes(), []byte("\n\r\n"))) {
+				if _, err := fn(unescapeMbox(msg.Bytes())); err == nil {
+					start = offset
+				}
+			}
+			if start >= 0 {
+				s.offset = start
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mbox %s: %w", s.Path, err)
+		}
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if start >= 0 {
+				if _, err := fn(unescapeMbox(msg.Bytes())); err != nil {
+					s.offset = start
+					return nil
+				}
+			}
+			msg.Reset()
+			start = offset
+			s.offset = start
+		} else if start >= 0 {
+			msg.Write(line)
+		}
+		offset += int64(len(line))
+	}
+}
+
+// unescapeMbox removes the ">" that mboxrd adds in front of body lines starting with "From ".
+func unescapeMbox(msg []byte) []byte {
+	lines := bytes.SplitAfter(msg, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimLeft(line, ">")
+		if len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+			lines[i] = line[1:]
+		}
+	}
+	return bytes.Join(lines, nil)
+}
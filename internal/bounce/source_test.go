@@ -0,0 +1,126 @@
+package bounce
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyFixture copies a testdata file into a temporary directory and returns its path.
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// subjects polls the source and returns the subjects of the messages it offered.
+func subjects(t *testing.T, s Source) []string {
+	t.Helper()
+	var got []string
+	err := s.Poll(func(data []byte) (bool, error) {
+		header, _, _ := strings.Cut(string(data), "\n\n")
+		for _, line := range strings.Split(header, "\n") {
+			if subject, ok := strings.CutPrefix(line, "Subject: "); ok {
+				got = append(got, strings.TrimSpace(subject))
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestMboxSourcePartialMessage(t *testing.T) {
+	path := copyFixture(t, "partial.mbox")
+	state := filepath.Join(t.TempDir(), "state", "mbox.offset")
+	s := &MboxSource{Path: path, StatePath: state}
+
+	got := subjects(t, s)
+	want := []string{"Undelivered Mail Returned to Sender", "Re: Welcome"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("first poll read %q, want %q", got, want)
+	}
+	if got := subjects(t, s); len(got) != 0 {
+		t.Fatalf("second poll read %q before the last message was complete", got)
+	}
+
+	// The mail system finishes writing the last message.
+	rest, err := os.ReadFile(filepath.Join("testdata", "partial.mbox.rest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(rest)
+	f.Close()
+
+	var last []byte
+	err = s.Poll(func(data []byte) (bool, error) {
+		last = data
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Parse(last)
+	if err != nil {
+		t.Fatalf("completed message does not parse: %v", err)
+	}
+	if report.MessageID != "1772499602.654321.7@mail.example.com" || len(report.Recipients) != 3 {
+		t.Errorf("completed message parsed as %+v", report)
+	}
+
+	// A restart continues after the last message.
+	restarted := &MboxSource{Path: path, StatePath: state}
+	if got := subjects(t, restarted); len(got) != 0 {
+		t.Errorf("poll after a restart read %q again", got)
+	}
+}
+
+func TestMboxSourceUnescapesFromLines(t *testing.T) {
+	s := &MboxSource{Path: copyFixture(t, "partial.mbox")}
+	var reply []byte
+	err := s.Poll(func(data []byte) (bool, error) {
+		if bytes.Contains(data, []byte("Subject: Re: Welcome")) {
+			reply = data
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(reply, []byte("\nFrom the team, with love.\n")) {
+		t.Errorf("escaped From line was not restored:\n%s", reply)
+	}
+}
+
+func TestMboxSourceRetriesFailedMessage(t *testing.T) {
+	s := &MboxSource{Path: copyFixture(t, "partial.mbox")}
+	calls := 0
+	err := s.Poll(func(data []byte) (bool, error) {
+		calls++
+		return false, os.ErrDeadlineExceeded
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("poll continued after a failed message: %d calls", calls)
+	}
+	if got := subjects(t, s); len(got) != 2 {
+		t.Errorf("failed message was not offered again: %q", got)
+	}
+}
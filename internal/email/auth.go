@@ -0,0 +1,156 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthMechanism selects how a relay connection authenticates.
+type AuthMechanism string
+
+const (
+	AuthPlain   AuthMechanism = "plain"
+	AuthLogin   AuthMechanism = "login"
+	AuthCRAMMD5 AuthMechanism = "cram-md5"
+	// AuthXOAUTH2 authenticates with an OAuth2 access token, as Gmail and Microsoft 365 expect.
+	AuthXOAUTH2 AuthMechanism = "xoauth2"
+	// AuthNone sends without authenticating, e.g. through a local relay on port 25.
+	AuthNone AuthMechanism = "none"
+)
+
+// AuthConfig controls how a relay connection authenticates. The username and password
+// come from the relay itself.
+type AuthConfig struct {
+	// Mechanism defaults to AuthPlain when a username is set and AuthNone otherwise.
+	Mechanism AuthMechanism
+	OAuth2    OAuth2Config
+}
+
+// OAuth2Config is used by AuthXOAUTH2 to obtain access tokens from a stored refresh token.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+}
+
+// AuthError reports that a relay refused to authenticate the connection, which no retry
+// of the message can fix.
+type AuthError struct {
+	Relay string
+	Err   error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("relay %s rejected the credentials: %v", e.Relay, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// withDefaults fills in the mechanism for the given username.
+func (a AuthConfig) withDefaults(username string) AuthConfig {
+	if a.Mechanism == "" {
+		a.Mechanism = AuthNone
+		if username != "" {
+			a.Mechanism = AuthPlain
+		}
+	}
+	return a
+}
+
+// build returns the smtp.Auth for the mechanism, or nil for AuthNone.
+func (a AuthConfig) build(username, password, host string) (smtp.Auth, error) {
+	switch a.Mechanism {
+	case AuthNone:
+		return nil, nil
+	case AuthPlain:
+		return smtp.PlainAuth("", username, password, host), nil
+	case AuthLogin:
+		return &loginAuth{username: username, password: password, host: host}, nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(username, password), nil
+	case AuthXOAUTH2:
+		o := a.OAuth2
+		if o.TokenURL == "" || o.ClientID == "" || o.RefreshToken == "" {
+			return nil, errors.New("xoauth2 requires token_url, client_id and refresh_token")
+		}
+		cfg := &oauth2.Config{
+			ClientID:     o.ClientID,
+			ClientSecret: o.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: o.TokenURL},
+		}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+		// The token source refreshes the access token whenever it has expired.
+		tokens := oauth2.ReuseTokenSource(nil, cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: o.RefreshToken}))
+		return &xoauth2Auth{username: username, tokens: tokens}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mechanism %q", a.Mechanism)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism. Like smtp.PlainAuth, it refuses to send
+// credentials over an unencrypted connection to anything but localhost.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:", "User Name\x00":
+		return []byte(a.username), nil
+	case "Password:", "Password\x00":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+// xoauth2Auth implements Google's and Microsoft's XOAUTH2 mechanism.
+type xoauth2Auth struct {
+	username string
+	tokens   oauth2.TokenSource
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	token, err := a.tokens.Token()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to refresh OAuth2 access token: %w", err)
+	}
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + token.AccessToken + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sent a JSON error; an empty reply makes it finish with the failure code.
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
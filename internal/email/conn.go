@@ -0,0 +1,239 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	// transactionTimeout bounds one message exchange so a stalled server cannot hold a connection forever.
+	transactionTimeout = 2 * time.Minute
+)
+
+// errPoolTimeout is returned when no pooled connection became free in time.
+var errPoolTimeout = errors.New("timed out waiting for a free SMTP connection")
+
+type conn struct {
+	*smtp.Client
+	net net.Conn
+}
+
+// connPool keeps up to size connections to one relay, dialing them on demand with the
+// relay's TLS mode and authentication.
+type connPool struct {
+	name  string
+	addr  string
+	host  string
+	mode  TLSMode
+	tls   *tls.Config
+	auth  smtp.Auth
+	idle  chan *conn
+	slots chan struct{}
+}
+
+func newConnPool(name, host string, port int, size int, tlsCfg TLSConfig, auth smtp.Auth) (*connPool, error) {
+	cfg, err := tlsCfg.build(host)
+	if err != nil {
+		return nil, err
+	}
+	return &connPool{
+		name:  name,
+		addr:  net.JoinHostPort(host, fmt.Sprint(port)),
+		host:  host,
+		mode:  tlsCfg.Mode,
+		tls:   cfg,
+		auth:  auth,
+		idle:  make(chan *conn, size),
+		slots: make(chan struct{}, size),
+	}, nil
+}
+
+// dial opens an authenticated connection. Failures to satisfy the TLS mode are returned as
+// *TLSError and credentials the server refuses as *AuthError.
+func (p *connPool) dial() (*conn, error) {
+	nc, err := net.DialTimeout("tcp", p.addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	nc.SetDeadline(time.Now().Add(dialTimeout))
+	if p.mode == TLSImplicit {
+		tc := tls.Client(nc, p.tls)
+		if err := tc.Handshake(); err != nil {
+			nc.Close()
+			return nil, &TLSError{Relay: p.name, Mode: p.mode, Err: err}
+		}
+		nc = tc
+	}
+	c, err := smtp.NewClient(nc, p.host)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if p.mode == TLSOpportunistic || p.mode == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(p.tls); err != nil {
+				c.Close()
+				// In opportunistic mode a refused STARTTLS command is an ordinary server error,
+				// but a relay that must use TLS and refuses it is a configuration problem.
+				var protoErr *textproto.Error
+				if errors.As(err, &protoErr) && p.mode != TLSStartTLS {
+					return nil, err
+				}
+				return nil, &TLSError{Relay: p.name, Mode: p.mode, Err: err}
+			}
+		} else if p.mode == TLSStartTLS {
+			c.Close()
+			return nil, &TLSError{Relay: p.name, Mode: p.mode, Err: errors.New("server does not offer STARTTLS")}
+		}
+	}
+	if p.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			c.Close()
+			return nil, &AuthError{Relay: p.name, Err: errors.New("server does not support AUTH")}
+		}
+		if err := c.Auth(p.auth); err != nil {
+			c.Close()
+			// Only a reply from the server proves the credentials are wrong; a connection or
+			// token endpoint failure may pass.
+			var protoErr *textproto.Error
+			if errors.As(err, &protoErr) {
+				return nil, &AuthError{Relay: p.name, Err: err}
+			}
+			return nil, err
+		}
+	}
+	return &conn{Client: c, net: nc}, nil
+}
+
+// get returns an idle connection that still answers, or dials a new one.
+func (p *connPool) get() (*conn, error) {
+	for {
+		select {
+		case c := <-p.idle:
+			c.net.SetDeadline(time.Now().Add(dialTimeout))
+			if c.Noop() == nil {
+				return c, nil
+			}
+			c.net.Close()
+		default:
+			return p.dial()
+		}
+	}
+}
+
+func (p *connPool) put(c *conn) {
+	select {
+	case p.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+// send delivers msg to its recipients and returns the server's reply to the message data.
+// The connection is kept for reuse unless the error suggests it is broken.
+func (p *connPool) send(msg *Encoded, timeout time.Duration) (string, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-time.After(timeout):
+		return "", errPoolTimeout
+	}
+	defer func() { <-p.slots }()
+
+	c, err := p.get()
+	if err != nil {
+		var authErr *AuthError
+		if errors.As(err, &authErr) {
+			return "", err
+		}
+		// Connection and TLS failures concern the relay rather than the message, so they
+		// are transient even when the server replied with a 5xx code.
+		return "", &SendError{Err: fmt.Errorf("failed to connect to relay %s: %w", p.name, err)}
+	}
+	c.net.SetDeadline(time.Now().Add(transactionTimeout))
+	resp, err := transaction(c.Client, msg)
+	if err != nil {
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) && c.Reset() == nil {
+			p.put(c)
+		} else {
+			c.net.Close()
+		}
+		return "", err
+	}
+	p.put(c)
+	return fmt.Sprintf("%s: %s", p.name, resp), nil
+}
+
+// transaction sends one message. DATA is issued by hand rather than through Client.Data,
+// whose writer discards the final reply that carries the server's queue ID. A CC or BCC
+// address the server refuses is left out, so that it does not fail the To recipients.
+func transaction(c *smtp.Client, msg *Encoded) (string, error) {
+	if err := c.Mail(msg.From); err != nil {
+		return "", err
+	}
+	accepted := 0
+	for i, rcpt := range msg.To {
+		if err := c.Rcpt(rcpt); err != nil {
+			var protoErr *textproto.Error
+			if errors.As(err, &protoErr) && i >= len(msg.To)-msg.Copies {
+				log.Printf("참조 수신자가 거부되어 제외합니다 (%s): %v", rcpt, err)
+				continue
+			}
+			return "", err
+		}
+		accepted++
+	}
+	if accepted == 0 {
+		return "", &SendError{Permanent: true, Err: errors.New("the server accepted none of the recipients")}
+	}
+	id, err := c.Text.Cmd("DATA")
+	if err != nil {
+		return "", err
+	}
+	c.Text.StartResponse(id)
+	_, _, err = c.Text.ReadResponse(354)
+	c.Text.EndResponse(id)
+	if err != nil {
+		return "", err
+	}
+	w := c.Text.DotWriter()
+	if _, err := w.Write(msg.Data); err != nil {
+		return "", err
+	}
+	// From here on the server may have the complete message. Only an explicit rejection
+	// proves it was not accepted; any other failure leaves the delivery uncertain.
+	if err := w.Close(); err != nil {
+		return "", uncertain(err)
+	}
+	code, text, err := c.Text.ReadResponse(250)
+	if err != nil {
+		return "", uncertain(err)
+	}
+	return fmt.Sprintf("%d %s", code, text), nil
+}
+
+// uncertain marks an error after the message data as an uncertain delivery, unless it is a
+// reply from the server.
+func uncertain(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return err
+	}
+	return &SendError{Uncertain: true, Err: fmt.Errorf("connection failed after the message was sent: %w", err)}
+}
+
+// check dials the relay once to verify that it satisfies the TLS mode and accepts the credentials.
+func (p *connPool) check() error {
+	c, err := p.dial()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,227 @@
+package email
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP is a plain-text SMTP server that accepts every message for the addresses it
+// does not refuse.
+type fakeSMTP struct {
+	ln net.Listener
+	// rejectRcpt maps an address to the reply refusing it.
+	rejectRcpt map[string]string
+	// authReply answers AUTH; any credentials are accepted when it is empty.
+	authReply string
+
+	mu       sync.Mutex
+	rcpts    []string
+	messages int
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, rejectRcpt: make(map[string]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) pool(t *testing.T) *connPool {
+	t.Helper()
+	p, err := newConnPool("fake", "127.0.0.1", s.ln.Addr().(*net.TCPAddr).Port, 1, TLSConfig{Mode: TLSNone}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 fake ESMTP\r\n")
+	data := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if data {
+			if line == "." {
+				data = false
+				s.mu.Lock()
+				s.messages++
+				s.mu.Unlock()
+				fmt.Fprint(conn, "250 2.0.0 Ok: queued as ABC123\r\n")
+			}
+			continue
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			fmt.Fprint(conn, "250-fake\r\n250 AUTH PLAIN\r\n")
+		case strings.HasPrefix(cmd, "AUTH"):
+			if s.authReply != "" {
+				fmt.Fprintf(conn, "%s\r\n", s.authReply)
+				continue
+			}
+			fmt.Fprint(conn, "235 2.7.0 Authentication successful\r\n")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			addr := strings.Trim(line[len("RCPT TO:"):], "<>")
+			if reply, ok := s.rejectRcpt[addr]; ok {
+				fmt.Fprintf(conn, "%s\r\n", reply)
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, addr)
+			s.mu.Unlock()
+			fmt.Fprint(conn, "250 2.1.5 Ok\r\n")
+		case cmd == "DATA":
+			data = true
+			fmt.Fprint(conn, "354 End data with <CR><LF>.<CR><LF>\r\n")
+		case cmd == "QUIT":
+			fmt.Fprint(conn, "221 Bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "250 Ok\r\n")
+		}
+	}
+}
+
+func TestSendSkipsRefusedCopies(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rejectRcpt["gone@example.com"] = "550 5.1.1 <gone@example.com>: Recipient address rejected"
+	server.rejectRcpt["full@example.com"] = "452 4.2.2 Mailbox full"
+	msg := &Message{
+		To:      []string{"user@example.com"},
+		Cc:      []string{"gone@example.com"},
+		Bcc:     []string{"full@example.com", "audit@example.com"},
+		Subject: "Hello",
+		HTML:    "Hello",
+	}
+	encoded, err := msg.Encode("noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.pool(t).send(encoded, dialTimeout)
+	if err != nil {
+		t.Fatalf("send failed for a refused copy: %v", err)
+	}
+	if !strings.Contains(resp, "queued as ABC123") {
+		t.Errorf("response = %q", resp)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got := strings.Join(server.rcpts, ","); got != "user@example.com,audit@example.com" || server.messages != 1 {
+		t.Errorf("delivered %d messages to %s", server.messages, got)
+	}
+}
+
+func TestSendFailsRefusedRecipient(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rejectRcpt["user@example.com"] = "550 5.1.1 <user@example.com>: Recipient address rejected"
+	msg := &Message{To: []string{"user@example.com"}, Cc: []string{"boss@example.com"}, Subject: "Hello", HTML: "Hello"}
+	encoded, err := msg.Encode("noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.pool(t).send(encoded, dialTimeout)
+	if sendErr := ClassifyError(err); err == nil || !sendErr.Permanent || sendErr.Code != 550 {
+		t.Fatalf("err = %v, want a permanent 550 rejection", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.messages != 0 {
+		t.Errorf("message was sent to the copies although the recipient was refused")
+	}
+}
+
+func TestSendFailsWhenNoRecipientAccepted(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rejectRcpt["boss@example.com"] = "550 5.1.1 rejected"
+	encoded := &Encoded{From: "noreply@example.com", To: []string{"boss@example.com"}, Copies: 1, Data: []byte("Subject: x\r\n\r\nx\r\n")}
+	_, err := server.pool(t).send(encoded, dialTimeout)
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || !sendErr.Permanent {
+		t.Fatalf("err = %v, want a permanent SendError", err)
+	}
+}
+
+// smtpClient returns an SMTPClient that logs in to the servers as relays in the given order.
+func smtpClient(t *testing.T, servers ...*fakeSMTP) *SMTPClient {
+	t.Helper()
+	cfg := SMTPConfig{From: "noreply@example.com", Username: "user", Password: "secret", TLS: TLSConfig{Mode: TLSNone}}
+	for i, s := range servers {
+		cfg.Relays = append(cfg.Relays, RelayConfig{
+			Name:     fmt.Sprintf("relay%d", i+1),
+			Host:     "127.0.0.1",
+			Port:     s.ln.Addr().(*net.TCPAddr).Port,
+			Username: "user",
+			Password: "secret",
+			Priority: i,
+		})
+	}
+	client, err := NewSMTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCheckRelaysFailsOnRejectedCredentials(t *testing.T) {
+	good := newFakeSMTP(t)
+	if err := smtpClient(t, good).CheckRelays(); err != nil {
+		t.Fatalf("CheckRelays: %v", err)
+	}
+	bad := newFakeSMTP(t)
+	bad.authReply = "535 5.7.8 Authentication credentials invalid"
+	err := smtpClient(t, good, bad).CheckRelays()
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Relay != "relay2" {
+		t.Fatalf("err = %v, want an AuthError for relay2", err)
+	}
+}
+
+func TestSendEmailSkipsRelayRejectingCredentials(t *testing.T) {
+	bad := newFakeSMTP(t)
+	bad.authReply = "535 5.7.8 Authentication credentials invalid"
+	good := newFakeSMTP(t)
+	client := smtpClient(t, bad, good)
+	msg := &Message{To: []string{"user@example.com"}, Subject: "Hello", HTML: "Hello"}
+	resp, err := client.SendEmail(msg)
+	if err != nil {
+		t.Fatalf("SendEmail did not fail over: %v", err)
+	}
+	if !strings.HasPrefix(resp, "relay2: ") {
+		t.Errorf("response = %q, want one from relay2", resp)
+	}
+	if health := client.Health(); health[0].Healthy || !health[1].Healthy {
+		t.Errorf("health = %+v, want relay1 unhealthy", health)
+	}
+
+	// With no relay accepting the credentials, retrying the message cannot help.
+	_, err = smtpClient(t, bad).SendEmail(msg)
+	var sendErr *SendError
+	var authErr *AuthError
+	if !errors.As(err, &sendErr) || !sendErr.Permanent || !errors.As(err, &authErr) {
+		t.Fatalf("err = %#v, want a permanent SendError wrapping an AuthError", err)
+	}
+}
@@ -0,0 +1,235 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DKIMConfig enables DKIM signing of outgoing messages. Signing is off when Domain is empty.
+type DKIMConfig struct {
+	Domain   string
+	Selector string
+	// KeyPath is a PEM file holding an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key.
+	KeyPath string
+}
+
+// dkimHeaders are signed when present in the message.
+var dkimHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-Id",
+	"Mime-Version", "Content-Type", "List-Unsubscribe",
+}
+
+// DKIMSigner adds a DKIM-Signature header (RFC 6376) using relaxed/relaxed canonicalization.
+type DKIMSigner struct {
+	Domain   string
+	Selector string
+	key      crypto.Signer
+	algo     string
+}
+
+// NewDKIMSigner loads the private key of the configuration.
+func NewDKIMSigner(cfg DKIMConfig) (*DKIMSigner, error) {
+	if cfg.Domain == "" || cfg.Selector == "" || cfg.KeyPath == "" {
+		return nil, errors.New("dkim requires domain, selector and key path")
+	}
+	data, err := os.ReadFile(cfg.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DKIM key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in DKIM key %s", cfg.KeyPath)
+	}
+	var key interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DKIM key %s: %w", cfg.KeyPath, err)
+	}
+	s := &DKIMSigner{Domain: cfg.Domain, Selector: cfg.Selector}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 1024 {
+			return nil, fmt.Errorf("DKIM RSA key must be at least 1024 bits, got %d", k.N.BitLen())
+		}
+		s.key, s.algo = k, "rsa-sha256"
+	case ed25519.PrivateKey:
+		s.key, s.algo = k, "ed25519-sha256"
+	default:
+		return nil, fmt.Errorf("unsupported DKIM key type %T", key)
+	}
+	return s, nil
+}
+
+// DNSName returns the name of the TXT record that publishes the public key.
+func (s *DKIMSigner) DNSName() string {
+	return s.Selector + "._domainkey." + s.Domain
+}
+
+// DNSRecord returns the value of the TXT record that publishes the public key.
+func (s *DKIMSigner) DNSRecord() (string, error) {
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", err
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub), nil
+	default:
+		return "", fmt.Errorf("unsupported DKIM key type %T", pub)
+	}
+}
+
+// Sign returns the message with a DKIM-Signature header prepended. Line endings are
+// normalized to CRLF first, since the signature covers the exact bytes on the wire.
+func (s *DKIMSigner) Sign(msg []byte) ([]byte, error) {
+	msg = toCRLF(msg)
+	header, body, ok := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !ok {
+		return nil, errors.New("message has no body separator")
+	}
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	fields := splitHeader(header)
+	var names []string
+	var signed bytes.Buffer
+	for _, name := range dkimHeaders {
+		// When a header occurs more than once, the last instance is signed (RFC 6376 5.4.2).
+		for i := len(fields) - 1; i >= 0; i-- {
+			if strings.EqualFold(fieldName(fields[i]), name) {
+				names = append(names, strings.ToLower(name))
+				signed.WriteString(relaxedHeader(fields[i]))
+				break
+			}
+		}
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s;\r\n t=%d; h=%s;\r\n bh=%s;\r\n b=",
+		s.algo, s.Domain, s.Selector, time.Now().Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]))
+	// The signature header itself is signed with an empty b= tag and no trailing CRLF.
+	signed.WriteString(strings.TrimSuffix(relaxedHeader("DKIM-Signature: "+value), "\r\n"))
+
+	// Ed25519 signs the digest itself (RFC 8463); RSA signs it with PKCS#1 v1.5.
+	opts := crypto.Hash(0)
+	if s.algo == "rsa-sha256" {
+		opts = crypto.SHA256
+	}
+	digest := sha256.Sum256(signed.Bytes())
+	sig, err := s.key.Sign(rand.Reader, digest[:], opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("DKIM-Signature: " + value)
+	encoded := base64.StdEncoding.EncodeToString(sig)
+	for len(encoded) > 72 {
+		out.WriteString(encoded[:72] + "\r\n ")
+		encoded = encoded[72:]
+	}
+	out.WriteString(encoded + "\r\n")
+	out.Write(msg)
+	return out.Bytes(), nil
+}
+
+// encodeSigned encodes the message and signs it with signer, if not nil.
+func encodeSigned(msg *Message, defaultFrom string, signer *DKIMSigner) (*Encoded, error) {
+	encoded, err := msg.Encode(defaultFrom)
+	if err != nil || signer == nil {
+		return encoded, err
+	}
+	if encoded.Data, err = signer.Sign(encoded.Data); err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
+// toCRLF converts bare LF line endings to CRLF.
+func toCRLF(msg []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(msg))
+	for i, b := range msg {
+		if b == '\n' && (i == 0 || msg[i-1] != '\r') {
+			out.WriteByte('\r')
+		}
+		out.WriteByte(b)
+	}
+	return out.Bytes()
+}
+
+// splitHeader splits a header block into fields, keeping folded lines with their field.
+func splitHeader(header []byte) []string {
+	var fields []string
+	for _, line := range strings.Split(string(header), "\r\n") {
+		if len(fields) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			fields[len(fields)-1] += "\r\n" + line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+func fieldName(field string) string {
+	name, _, _ := strings.Cut(field, ":")
+	return strings.TrimSpace(name)
+}
+
+// relaxedHeader canonicalizes a header field with the "relaxed" algorithm (RFC 6376 3.4.2).
+func relaxedHeader(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	value = strings.NewReplacer("\r\n", "").Replace(value)
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// relaxedBody canonicalizes a body with the "relaxed" algorithm (RFC 6376 3.4.4).
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		var sb strings.Builder
+		space := false
+		for j := 0; j < len(line); j++ {
+			if line[j] == ' ' || line[j] == '\t' {
+				space = true
+				continue
+			}
+			if space {
+				sb.WriteByte(' ')
+				space = false
+			}
+			sb.WriteByte(line[j])
+		}
+		lines[i] = sb.String()
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
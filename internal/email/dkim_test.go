@@ -0,0 +1,230 @@
+package email
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// rfc8463Message is the signed example of RFC 8463 appendix A.3, whose public key is
+// rfc8463PublicKey and whose private key seed is rfc8463Seed.
+const rfc8463Message = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+	" d=football.example.com; i=@football.example.com;\r\n" +
+	" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+	" subject : date : message-id : from : subject : date;\r\n" +
+	" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+	" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+	" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+	"From: Joe SixPack <joe@football.example.com>\r\n" +
+	"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+	"Subject: Is dinner ready?\r\n" +
+	"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+	"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+	"\r\n" +
+	"Hi.\r\n" +
+	"\r\n" +
+	"We lost the game.  Are you hungry yet?\r\n" +
+	"\r\n" +
+	"Joe.\r\n"
+
+const (
+	rfc8463PublicKey = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+	rfc8463Seed      = "nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A="
+	rfc8463BodyHash  = "2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8="
+)
+
+var (
+	wspRun   = regexp.MustCompile(`[ \t]+`)
+	emptyTag = regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`)
+)
+
+// verifyDKIM checks the first DKIM-Signature of msg against pub. It is written apart from
+// the signer, following RFC 6376 section 6, so that both cannot share a mistake.
+func verifyDKIM(msg string, pub crypto.PublicKey) error {
+	header, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		return fmt.Errorf("no body separator")
+	}
+	var fields []string
+	for _, line := range strings.Split(header, "\r\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			fields[len(fields)-1] += "\r\n" + line
+		} else {
+			fields = append(fields, line)
+		}
+	}
+	if !strings.HasPrefix(strings.ToLower(fields[0]), "dkim-signature:") {
+		return fmt.Errorf("first header is not a DKIM-Signature: %q", fields[0])
+	}
+	sigField, fields := fields[0], fields[1:]
+
+	tags := make(map[string]string)
+	_, value, _ := strings.Cut(sigField, ":")
+	for _, tag := range strings.Split(value, ";") {
+		name, v, _ := strings.Cut(tag, "=")
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(v), "")
+	}
+	if tags["c"] != "relaxed/relaxed" {
+		return fmt.Errorf("unexpected canonicalization %q", tags["c"])
+	}
+
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(wspRun.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	canonicalBody := ""
+	if len(lines) > 0 {
+		canonicalBody = strings.Join(lines, "\r\n") + "\r\n"
+	}
+	bodyHash := sha256.Sum256([]byte(canonicalBody))
+	if got := base64.StdEncoding.EncodeToString(bodyHash[:]); got != tags["bh"] {
+		return fmt.Errorf("body hash = %s, signature says %s", got, tags["bh"])
+	}
+
+	canonical := func(field string) string {
+		name, value, _ := strings.Cut(field, ":")
+		value = wspRun.ReplaceAllString(strings.ReplaceAll(value, "\r\n", ""), " ")
+		return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(value) + "\r\n"
+	}
+	var data strings.Builder
+	used := make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i >= 0; i-- {
+			fieldName, _, _ := strings.Cut(fields[i], ":")
+			if !used[i] && strings.EqualFold(strings.TrimSpace(fieldName), name) {
+				used[i] = true
+				data.WriteString(canonical(fields[i]))
+				break
+			}
+		}
+	}
+	unsigned := emptyTag.ReplaceAllString(sigField, "$1$2")
+	data.WriteString(strings.TrimSuffix(canonical(unsigned), "\r\n"))
+	digest := sha256.Sum256([]byte(data.String()))
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fmt.Errorf("invalid b= tag: %w", err)
+	}
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		if tags["a"] != "ed25519-sha256" || !ed25519.Verify(key, digest[:], sig) {
+			return fmt.Errorf("ed25519 signature does not verify")
+		}
+	case *rsa.PublicKey:
+		if tags["a"] != "rsa-sha256" {
+			return fmt.Errorf("unexpected algorithm %q", tags["a"])
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("rsa signature does not verify: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported key %T", pub)
+	}
+	return nil
+}
+
+// writeKey stores key as a PKCS#8 PEM file and returns its path.
+func writeKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDKIMVerifierRFC8463(t *testing.T) {
+	pub, _ := base64.StdEncoding.DecodeString(rfc8463PublicKey)
+	if err := verifyDKIM(rfc8463Message, ed25519.PublicKey(pub)); err != nil {
+		t.Fatalf("RFC 8463 example does not verify: %v", err)
+	}
+	tampered := strings.Replace(rfc8463Message, "Is dinner ready?", "Is  dinner ready?!", 1)
+	if err := verifyDKIM(tampered, ed25519.PublicKey(pub)); err == nil {
+		t.Fatal("tampered message verified")
+	}
+}
+
+func TestDKIMSignRFC8463(t *testing.T) {
+	seed, _ := base64.StdEncoding.DecodeString(rfc8463Seed)
+	key := ed25519.NewKeyFromSeed(seed)
+	signer, err := NewDKIMSigner(DKIMConfig{Domain: "football.example.com", Selector: "brisbane", KeyPath: writeKey(t, key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := signer.DNSRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v=DKIM1; k=ed25519; p=" + rfc8463PublicKey; record != want {
+		t.Errorf("DNSRecord = %q, want %q", record, want)
+	}
+	_, unsigned, _ := strings.Cut(rfc8463Message, "==\r\n")
+	signed, err := signer.Sign([]byte(unsigned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(signed), "bh="+rfc8463BodyHash+";") {
+		t.Errorf("body hash differs from RFC 8463:\n%s", signed)
+	}
+	if err := verifyDKIM(string(signed), key.Public()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDKIMSignEncodedMessage(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{
+		To:      []string{"Suzie Q <suzie@example.net>"},
+		Cc:      []string{"joe@example.net"},
+		ReplyTo: []string{"help@example.com"},
+		Subject: "긴 제목은 인코딩된 뒤 여러 줄로 접힐 수 있습니다 — folded subject with trailing  spaces  ",
+		HTML:    "<p>Hello,  world</p>\n\n\n",
+		Text:    "Hello,\t world  \n",
+		Headers: map[string]string{"List-Unsubscribe": "<mailto:unsubscribe@example.com>", "X-Campaign": "spring"},
+	}
+	for _, key := range []crypto.Signer{rsaKey, edKey} {
+		t.Run(fmt.Sprintf("%T", key), func(t *testing.T) {
+			signer, err := NewDKIMSigner(DKIMConfig{Domain: "example.com", Selector: "mail", KeyPath: writeKey(t, key)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := encodeSigned(msg, "Sender <noreply@example.com>", signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verifyDKIM(string(encoded.Data), key.Public()); err != nil {
+				t.Fatalf("%v\n%s", err, encoded.Data)
+			}
+			tampered := strings.Replace(string(encoded.Data), "suzie@example.net", "mallory@example.net", 1)
+			if err := verifyDKIM(tampered, key.Public()); err == nil {
+				t.Error("message with a changed To header verified")
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package email
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileFormat selects how a FileTransport lays out the messages it writes.
+type FileFormat string
+
+const (
+	// FileFormatEML writes every message as a separate .eml file in the directory.
+	FileFormatEML FileFormat = "eml"
+	// FileFormatMaildir delivers into the new/ folder of a maildir, so any mail client can read it.
+	FileFormatMaildir FileFormat = "maildir"
+)
+
+// FileTransport is a Sender that drops messages into a local directory instead of delivering them.
+type FileTransport struct {
+	Dir         string
+	Format      FileFormat
+	DefaultFrom string
+	// DKIM is nil when signing is off.
+	DKIM *DKIMSigner
+}
+
+// NewFileTransport creates the directory layout for the format.
+func NewFileTransport(dir string, format FileFormat, from string) (*FileTransport, error) {
+	if format == "" {
+		format = FileFormatEML
+	}
+	var dirs []string
+	switch format {
+	case FileFormatEML:
+		dirs = []string{dir}
+	case FileFormatMaildir:
+		dirs = []string{filepath.Join(dir, "tmp"), filepath.Join(dir, "new"), filepath.Join(dir, "cur")}
+	default:
+		return nil, fmt.Errorf("unknown file format %q", format)
+	}
+	for _, d := range dirs {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create mail directory: %w", err)
+		}
+	}
+	return &FileTransport{Dir: dir, Format: format, DefaultFrom: from}, nil
+}
+
+// Encode serializes the message and signs it if DKIM is configured.
+func (t *FileTransport) Encode(msg *Message) (*Encoded, error) {
+	return encodeSigned(msg, t.DefaultFrom, t.DKIM)
+}
+
+// SendEmail writes the message to a uniquely named file. The file is written under a
+// temporary name first and renamed, so readers never see a partial message.
+func (t *FileTransport) SendEmail(msg *Message) (string, error) {
+	encoded := msg.Encoded
+	if encoded == nil {
+		var err error
+		if encoded, err = t.Encode(msg); err != nil {
+			return "", &SendError{Permanent: true, Err: err}
+		}
+	}
+	name, err := uniqueName()
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(t.Dir, "."+name+".tmp")
+	final := filepath.Join(t.Dir, name+".eml")
+	if t.Format == FileFormatMaildir {
+		tmp = filepath.Join(t.Dir, "tmp", name)
+		final = filepath.Join(t.Dir, "new", name)
+	}
+	if err := os.WriteFile(tmp, encoded.Data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	return "saved to " + final, nil
+}
+
+func (t *FileTransport) From() string {
+	return t.DefaultFrom
+}
+
+func (t *FileTransport) Concurrency() int {
+	return defaultPoolSize
+}
+
+// uniqueName returns a maildir-style unique file name: time, random part and host name.
+func uniqueName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	// Maildir reserves "/" and ":" in file names.
+	host = strings.NewReplacer("/", "\\057", ":", "\\072").Replace(host)
+	return fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), hex.EncodeToString(b), host), nil
+}
@@ -0,0 +1,282 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPConfig configures an HTTPTransport for a transactional provider's JSON API.
+type HTTPConfig struct {
+	Endpoint string
+	// Method defaults to POST.
+	Method string
+	// AuthHeader is the header carrying the API key, e.g. "Authorization" with
+	// AuthValue "Bearer <key>", or "X-Api-Key" with the bare key.
+	AuthHeader string
+	AuthValue  string
+	// Headers are sent with every request in addition to the auth header.
+	Headers map[string]string
+	// From is used for messages that do not set their own.
+	From string
+	// Timeout bounds a single request. Defaults to 30 seconds.
+	Timeout time.Duration
+	// Concurrency is the number of requests sent in parallel. Defaults to 10.
+	Concurrency int
+	Fields      HTTPFieldMapping
+}
+
+// HTTPFieldMapping names the JSON fields of the request body. A dot in a name nests the
+// value in an object, so "content.html" becomes {"content": {"html": ...}}. Unset names
+// fall back to DefaultHTTPFieldMapping, and "-" leaves the value out of the request.
+type HTTPFieldMapping struct {
+	From    string
+	To      string
+	Cc      string
+	Bcc     string
+	ReplyTo string
+	Subject string
+	HTML    string
+	Text    string
+	// Headers receives the extra headers of the message as an object, together with the
+	// Message-Id the message was encoded with. Without it, bounces cannot be matched to
+	// the message they are about.
+	Headers string
+	// Attachments receives the inline images and attachments as a list of objects with the
+	// Attachment* keys below. Content is base64 encoded.
+	Attachments           string
+	AttachmentFilename    string
+	AttachmentContent     string
+	AttachmentContentType string
+	// AttachmentContentID receives the Content-ID (without angle brackets) of inline images,
+	// which the HTML references as "cid:<id>".
+	AttachmentContentID string
+}
+
+// DefaultHTTPFieldMapping is used for any field of an HTTPFieldMapping that is left unset.
+var DefaultHTTPFieldMapping = HTTPFieldMapping{
+	From:                  "from",
+	To:                    "to",
+	Cc:                    "cc",
+	Bcc:                   "bcc",
+	ReplyTo:               "reply_to",
+	Subject:               "subject",
+	HTML:                  "html",
+	Text:                  "text",
+	Headers:               "headers",
+	Attachments:           "attachments",
+	AttachmentFilename:    "filename",
+	AttachmentContent:     "content",
+	AttachmentContentType: "content_type",
+	AttachmentContentID:   "content_id",
+}
+
+func (f HTTPFieldMapping) withDefaults() HTTPFieldMapping {
+	fill := func(field *string, def string) {
+		if *field == "" {
+			*field = def
+		}
+	}
+	d := DefaultHTTPFieldMapping
+	fill(&f.From, d.From)
+	fill(&f.To, d.To)
+	fill(&f.Cc, d.Cc)
+	fill(&f.Bcc, d.Bcc)
+	fill(&f.ReplyTo, d.ReplyTo)
+	fill(&f.Subject, d.Subject)
+	fill(&f.HTML, d.HTML)
+	fill(&f.Text, d.Text)
+	fill(&f.Headers, d.Headers)
+	fill(&f.Attachments, d.Attachments)
+	fill(&f.AttachmentFilename, d.AttachmentFilename)
+	fill(&f.AttachmentContent, d.AttachmentContent)
+	fill(&f.AttachmentContentType, d.AttachmentContentType)
+	fill(&f.AttachmentContentID, d.AttachmentContentID)
+	return f
+}
+
+// HTTPTransport is a Sender that delivers through a provider's HTTP JSON API.
+type HTTPTransport struct {
+	Config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPTransport creates an HTTPTransport, filling in defaults.
+func NewHTTPTransport(cfg HTTPConfig) (*HTTPTransport, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("http transport requires an endpoint")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultPoolSize
+	}
+	cfg.Fields = cfg.Fields.withDefaults()
+	return &HTTPTransport{
+		Config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// SendEmail posts the message to the API, encoding it first unless msg.Encoded is set so
+// that the request carries the Message-Id of the encoded message. 2xx responses are
+// successes; 408, 429 and 5xx responses and network errors before the request was written
+// are transient, and any other status is permanent. A timeout or lost connection after the
+// request was written is uncertain, since the provider may already have accepted the mail.
+func (t *HTTPTransport) SendEmail(msg *Message) (string, error) {
+	encoded := msg.Encoded
+	if encoded == nil {
+		var err error
+		if encoded, err = t.Encode(msg); err != nil {
+			return "", &SendError{Permanent: true, Err: err}
+		}
+	}
+	body, err := json.Marshal(t.payload(msg, encoded.MessageID()))
+	if err != nil {
+		return "", &SendError{Permanent: true, Err: err}
+	}
+	req, err := http.NewRequest(t.Config.Method, t.Config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", &SendError{Permanent: true, Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.Config.Headers {
+		req.Header.Set(name, value)
+	}
+	if t.Config.AuthHeader != "" {
+		req.Header.Set(t.Config.AuthHeader, t.Config.AuthValue)
+	}
+	var written atomic.Bool
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				written.Store(true)
+			}
+		},
+	}))
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if written.Load() {
+			return "", &SendError{Uncertain: true, Err: fmt.Errorf("no response after the request was sent: %w", err)}
+		}
+		return "", &SendError{Err: err}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	response := strings.TrimSpace(resp.Status + " " + strings.TrimSpace(string(respBody)))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return response, nil
+	}
+	transient := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return "", &SendError{
+		Code:      resp.StatusCode,
+		Permanent: !transient,
+		Err:       errors.New(response),
+	}
+}
+
+// Encode returns the MIME equivalent of the API request. The API builds the actual message,
+// so only its Message-Id is guaranteed to match what the provider sends.
+func (t *HTTPTransport) Encode(msg *Message) (*Encoded, error) {
+	return msg.Encode(t.Config.From)
+}
+
+func (t *HTTPTransport) From() string {
+	return t.Config.From
+}
+
+func (t *HTTPTransport) Concurrency() int {
+	return t.Config.Concurrency
+}
+
+// payload builds the request body according to the field mapping.
+func (t *HTTPTransport) payload(msg *Message, messageID string) map[string]interface{} {
+	f := t.Config.Fields
+	from := msg.From
+	if from == "" {
+		from = t.Config.From
+	}
+	body := make(map[string]interface{})
+	setField(body, f.From, from)
+	setField(body, f.To, msg.To)
+	setField(body, f.Subject, msg.Subject)
+	setField(body, f.HTML, msg.HTML)
+	if msg.Text != "" {
+		setField(body, f.Text, msg.Text)
+	}
+	if len(msg.Cc) > 0 {
+		setField(body, f.Cc, msg.Cc)
+	}
+	if len(msg.Bcc) > 0 {
+		setField(body, f.Bcc, msg.Bcc)
+	}
+	if len(msg.ReplyTo) > 0 {
+		setField(body, f.ReplyTo, msg.ReplyTo)
+	}
+	headers := make(map[string]string, len(msg.Headers)+1)
+	for name, value := range msg.Headers {
+		headers[name] = value
+	}
+	if messageID != "" {
+		headers["Message-Id"] = "<" + messageID + ">"
+	}
+	if len(headers) > 0 {
+		setField(body, f.Headers, headers)
+	}
+	if len(msg.Attachments) > 0 {
+		attachments := make([]map[string]interface{}, 0, len(msg.Attachments))
+		for _, a := range msg.Attachments {
+			attachment := make(map[string]interface{})
+			setField(attachment, f.AttachmentFilename, a.Filename)
+			setField(attachment, f.AttachmentContent, base64.StdEncoding.EncodeToString(a.Content))
+			setField(attachment, f.AttachmentContentType, a.ContentType)
+			if id := contentID(a.Header); id != "" {
+				setField(attachment, f.AttachmentContentID, id)
+			}
+			attachments = append(attachments, attachment)
+		}
+		setField(body, f.Attachments, attachments)
+	}
+	return body
+}
+
+// setField stores value under the dotted path, creating nested objects as needed.
+func setField(obj map[string]interface{}, path string, value interface{}) {
+	if path == "" || path == "-" {
+		return
+	}
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+// contentID returns the Content-ID of an attachment without angle brackets. RenderTemplate
+// sets the header under the non-canonical key "Content-ID", and encoding the message adds a
+// canonical "Content-Id" derived from the filename when it does not find it, so the key
+// RenderTemplate used is looked up first.
+func contentID(header textproto.MIMEHeader) string {
+	if values := header["Content-ID"]; len(values) > 0 {
+		return strings.Trim(values[0], "<>")
+	}
+	return strings.Trim(header.Get("Content-ID"), "<>")
+}
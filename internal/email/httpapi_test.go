@@ -0,0 +1,188 @@
+package email
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/jordan-wright/email"
+)
+
+// apiServer starts a server that records the request body and answers with status.
+func apiServer(t *testing.T, status int, got *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("auth header = %q, want %q", r.Header.Get("X-Api-Key"), "secret")
+		}
+		data, _ := io.ReadAll(r.Body)
+		if got != nil {
+			if err := json.Unmarshal(data, got); err != nil {
+				t.Errorf("request body is not JSON: %v", err)
+			}
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, `{"id":"abc"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPTransportFieldMapping(t *testing.T) {
+	var got map[string]interface{}
+	srv := apiServer(t, http.StatusAccepted, &got)
+	transport, err := NewHTTPTransport(HTTPConfig{
+		Endpoint:   srv.URL,
+		AuthHeader: "X-Api-Key",
+		AuthValue:  "secret",
+		From:       "noreply@example.com",
+		Fields: HTTPFieldMapping{
+			HTML:                "content.html",
+			Text:                "content.text",
+			Bcc:                 "-",
+			AttachmentContentID: "cid",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{
+		To:      []string{"user@example.com"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Hello",
+		HTML:    `<img src="cid:logo">`,
+		Text:    "Hello",
+		Headers: map[string]string{"X-Campaign": "spring"},
+		Attachments: []email.Attachment{{
+			Filename:    "logo.png",
+			ContentType: "image/png",
+			Header:      textproto.MIMEHeader{"Content-ID": {"<logo>"}},
+			Content:     []byte("png"),
+		}},
+	}
+	if msg.Encoded, err = transport.Encode(msg); err != nil {
+		t.Fatal(err)
+	}
+	response, err := transport.SendEmail(msg)
+	if err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+	if response != `202 Accepted {"id":"abc"}` {
+		t.Errorf("response = %q", response)
+	}
+
+	if got["from"] != "noreply@example.com" || got["subject"] != "Hello" {
+		t.Errorf("from/subject = %v/%v", got["from"], got["subject"])
+	}
+	if _, ok := got["bcc"]; ok {
+		t.Errorf("bcc mapped to \"-\" was sent: %v", got["bcc"])
+	}
+	content, _ := got["content"].(map[string]interface{})
+	if content["html"] != msg.HTML || content["text"] != "Hello" {
+		t.Errorf("content = %v", got["content"])
+	}
+	headers, _ := got["headers"].(map[string]interface{})
+	if headers["X-Campaign"] != "spring" {
+		t.Errorf("headers = %v", got["headers"])
+	}
+	if want := "<" + msg.Encoded.MessageID() + ">"; msg.Encoded.MessageID() == "" || headers["Message-Id"] != want {
+		t.Errorf("Message-Id = %v, want %s", headers["Message-Id"], want)
+	}
+	attachments, _ := got["attachments"].([]interface{})
+	if len(attachments) != 1 {
+		t.Fatalf("attachments = %v", got["attachments"])
+	}
+	attachment := attachments[0].(map[string]interface{})
+	if attachment["filename"] != "logo.png" || attachment["content_type"] != "image/png" || attachment["cid"] != "logo" {
+		t.Errorf("attachment = %v", attachment)
+	}
+	if attachment["content"] != base64.StdEncoding.EncodeToString([]byte("png")) {
+		t.Errorf("attachment content = %v", attachment["content"])
+	}
+}
+
+func TestHTTPTransportErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusTooManyRequests, false},
+		{http.StatusRequestTimeout, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusServiceUnavailable, false},
+		{http.StatusBadRequest, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusUnprocessableEntity, true},
+	}
+	for _, tt := range tests {
+		srv := apiServer(t, tt.status, nil)
+		transport, err := NewHTTPTransport(HTTPConfig{
+			Endpoint:   srv.URL,
+			AuthHeader: "X-Api-Key",
+			AuthValue:  "secret",
+			From:       "noreply@example.com",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = transport.SendEmail(&Message{To: []string{"user@example.com"}, Subject: "Hello", HTML: "Hello"})
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("status %d: err = %v, want a SendError", tt.status, err)
+		}
+		if sendErr.Code != tt.status || sendErr.Permanent != tt.permanent {
+			t.Errorf("status %d: code = %d, permanent = %v, want permanent = %v", tt.status, sendErr.Code, sendErr.Permanent, tt.permanent)
+		}
+	}
+}
+
+func TestHTTPTransportTimeoutAfterRequestIsUncertain(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		received <- struct{}{}
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	transport, err := NewHTTPTransport(HTTPConfig{
+		Endpoint: srv.URL,
+		From:     "noreply@example.com",
+		Timeout:  100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transport.SendEmail(&Message{To: []string{"user@example.com"}, Subject: "Hello", HTML: "Hello"})
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || !sendErr.Uncertain || sendErr.Permanent {
+		t.Fatalf("err = %#v, want an uncertain SendError", err)
+	}
+	select {
+	case <-received:
+	default:
+		t.Fatal("the server never received the request")
+	}
+}
+
+func TestHTTPTransportConnectErrorIsTransient(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	endpoint := srv.URL
+	srv.Close()
+	transport, err := NewHTTPTransport(HTTPConfig{Endpoint: endpoint, From: "noreply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transport.SendEmail(&Message{To: []string{"user@example.com"}, Subject: "Hello", HTML: "Hello"})
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || sendErr.Uncertain || sendErr.Permanent {
+		t.Fatalf("err = %#v, want a transient SendError", err)
+	}
+}
@@ -0,0 +1,61 @@
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// DefaultAccount is the name of the SMTP account configured directly under the smtp section.
+const DefaultAccount = "default"
+
+// Identity is a named sender address that a send job can use instead of the global From.
+type Identity struct {
+	Name        string
+	DisplayName string
+	Address     string
+	// Account is the SMTP account the identity sends through. Defaults to DefaultAccount.
+	Account string
+	// Users and Groups restrict who may send as this identity. If both are empty, everyone may.
+	Users  []string
+	Groups []string
+}
+
+// From returns the identity formatted for the From header.
+func (i Identity) From() string {
+	return (&mail.Address{Name: i.DisplayName, Address: i.Address}).String()
+}
+
+// Allowed reports whether the user with the given email and groups may send as this identity.
+func (i Identity) Allowed(user string, groups []string) bool {
+	if len(i.Users) == 0 && len(i.Groups) == 0 {
+		return true
+	}
+	for _, u := range i.Users {
+		if strings.EqualFold(u, user) {
+			return true
+		}
+	}
+	for _, g := range i.Groups {
+		for _, ug := range groups {
+			if g == ug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks the identity's address and that its account exists.
+func (i Identity) Validate(accounts map[string]*Account) error {
+	if i.Name == "" {
+		return fmt.Errorf("sender identity without a name")
+	}
+	if _, err := mail.ParseAddress(i.Address); err != nil {
+		return fmt.Errorf("sender identity %s has an invalid address %q: %w", i.Name, i.Address, err)
+	}
+	if _, ok := accounts[i.Account]; !ok {
+		return fmt.Errorf("sender identity %s uses unknown SMTP account %q", i.Name, i.Account)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package email
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CapturedMessage is a message kept by a MemoryTransport.
+type CapturedMessage struct {
+	Message *Message
+	Encoded *Encoded
+	SentAt  time.Time
+}
+
+// MemoryTransport is a Sender that keeps every message in memory instead of delivering it.
+type MemoryTransport struct {
+	DefaultFrom string
+	mu          sync.Mutex
+	messages    []CapturedMessage
+}
+
+// NewMemoryTransport creates a MemoryTransport using from as the default From address.
+func NewMemoryTransport(from string) *MemoryTransport {
+	return &MemoryTransport{DefaultFrom: from}
+}
+
+func (t *MemoryTransport) SendEmail(msg *Message) (string, error) {
+	encoded := msg.Encoded
+	if encoded == nil {
+		var err error
+		if encoded, err = t.Encode(msg); err != nil {
+			return "", &SendError{Permanent: true, Err: err}
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, CapturedMessage{Message: msg, Encoded: encoded, SentAt: time.Now()})
+	return fmt.Sprintf("captured as message %d", len(t.messages)), nil
+}
+
+func (t *MemoryTransport) Encode(msg *Message) (*Encoded, error) {
+	return msg.Encode(t.DefaultFrom)
+}
+
+func (t *MemoryTransport) From() string {
+	return t.DefaultFrom
+}
+
+func (t *MemoryTransport) Concurrency() int {
+	return defaultPoolSize
+}
+
+// Messages returns the captured messages in the order they were sent.
+func (t *MemoryTransport) Messages() []CapturedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]CapturedMessage(nil), t.messages...)
+}
+
+// Reset discards the captured messages.
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
@@ -0,0 +1,168 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/jordan-wright/email"
+)
+
+// Message is a single email ready to be handed to the SMTP client.
+type Message struct {
+	// From overrides the SMTP account's From address, e.g. with a sender identity.
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     []string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []email.Attachment
+	// Headers are extra headers such as X-Campaign or Precedence. See ValidateHeaders.
+	Headers map[string]string
+	// Encoded, if set, is delivered as-is instead of encoding the message again, so that
+	// retries send identical bytes and callers can keep a copy of what was sent. See Sender.Encode.
+	Encoded *Encoded
+}
+
+// NewMessage builds a message for the rendered email.
+func NewMessage(to []string, subject string, rendered *RenderedEmail) *Message {
+	return &Message{
+		To:          to,
+		Subject:     subject,
+		HTML:        rendered.HTML,
+		Text:        rendered.Text,
+		Attachments: rendered.Attachments,
+	}
+}
+
+// reservedHeaders are set by the email package itself and cannot be overridden by extra headers.
+var reservedHeaders = map[string]bool{
+	"From": true, "Sender": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true,
+	"Subject": true, "Date": true, "Message-Id": true, "Mime-Version": true,
+	"Content-Type": true, "Content-Transfer-Encoding": true, "Return-Path": true,
+}
+
+// ValidateAddresses checks that every entry is a valid RFC 5322 address.
+func ValidateAddresses(addrs []string) error {
+	for _, addr := range addrs {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// ValidateHeaders checks that extra headers have valid names, single-line values
+// and do not replace a header the email package manages itself.
+func ValidateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" {
+			return fmt.Errorf("empty header name")
+		}
+		for _, r := range name {
+			// RFC 5322 field names are printable US-ASCII except the colon.
+			if r < 33 || r > 126 || r == ':' {
+				return fmt.Errorf("invalid header name %q", name)
+			}
+		}
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return fmt.Errorf("header %q cannot be overridden", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %q must not contain line breaks", name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the recipients and extra headers of the message.
+func (m *Message) Validate() error {
+	if len(m.To) == 0 {
+		return fmt.Errorf("message has no recipient")
+	}
+	if m.From != "" {
+		if _, err := mail.ParseAddress(m.From); err != nil {
+			return fmt.Errorf("invalid from address %q: %w", m.From, err)
+		}
+	}
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc, m.ReplyTo} {
+		if err := ValidateAddresses(list); err != nil {
+			return err
+		}
+	}
+	return ValidateHeaders(m.Headers)
+}
+
+// Encoded is a message serialized for delivery, together with its SMTP envelope.
+type Encoded struct {
+	// From is the envelope sender address.
+	From string
+	// To holds the bare addresses of every recipient: the To recipients first, then CC and BCC.
+	To []string
+	// Copies is the number of CC and BCC addresses at the end of To. A relay refusing one
+	// of them does not fail the message for the others.
+	Copies int
+	Data   []byte
+}
+
+// Encode validates the message and serializes it with the "github.com/jordan-wright/email"
+// library. defaultFrom is used when the message has no From of its own.
+func (m *Message) Encode(defaultFrom string) (*Encoded, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	e := email.NewEmail()
+	e.From = defaultFrom
+	if m.From != "" {
+		e.From = m.From
+	}
+	e.To = m.To
+	e.Cc = m.Cc
+	e.Bcc = m.Bcc
+	// The library Q-encodes the whole Reply-To value when it is not ASCII, which breaks the
+	// address, so display names are encoded here the way it does for From, To and Cc.
+	for _, addr := range m.ReplyTo {
+		parsed, _ := mail.ParseAddress(addr)
+		e.ReplyTo = append(e.ReplyTo, parsed.String())
+	}
+	e.Subject = m.Subject
+	e.HTML = []byte(m.HTML)
+	e.Text = []byte(m.Text)
+	for name, value := range m.Headers {
+		e.Headers.Set(name, value)
+	}
+	for _, attachment := range m.Attachments {
+		e.Attachments = append(e.Attachments, &attachment)
+	}
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", e.From, err)
+	}
+	data, err := e.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	encoded := &Encoded{From: from.Address, Data: data, Copies: len(m.Cc) + len(m.Bcc)}
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, addr := range list {
+			parsed, _ := mail.ParseAddress(addr)
+			encoded.To = append(encoded.To, parsed.Address)
+		}
+	}
+	return encoded, nil
+}
+
+// MessageID returns the Message-Id header of the encoded message without angle brackets,
+// or an empty string if it has none.
+func (e *Encoded) MessageID() string {
+	msg, err := mail.ReadMessage(bytes.NewReader(e.Data))
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>")
+}
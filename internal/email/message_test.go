@@ -0,0 +1,50 @@
+package email
+
+import (
+	"bytes"
+	"net/mail"
+	"testing"
+)
+
+func TestEncodeNonASCIIDisplayNames(t *testing.T) {
+	msg := &Message{
+		To:      []string{"지원자 <applicant@example.com>"},
+		Cc:      []string{"인사팀 <hr@example.com>"},
+		ReplyTo: []string{"모집팀장 <lead@example.com>", "help@example.com"},
+		Subject: "합격 안내",
+		HTML:    "<p>안녕하세요</p>",
+	}
+	encoded, err := msg.Encode("동아리 <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(encoded.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		header string
+		want   []mail.Address
+	}{
+		{"From", []mail.Address{{Name: "동아리", Address: "noreply@example.com"}}},
+		{"To", []mail.Address{{Name: "지원자", Address: "applicant@example.com"}}},
+		{"Cc", []mail.Address{{Name: "인사팀", Address: "hr@example.com"}}},
+		{"Reply-To", []mail.Address{{Name: "모집팀장", Address: "lead@example.com"}, {Address: "help@example.com"}}},
+	}
+	for _, tt := range tests {
+		got, err := parsed.Header.AddressList(tt.header)
+		if err != nil {
+			t.Errorf("%s: %v (raw %q)", tt.header, err, parsed.Header.Get(tt.header))
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.header, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if *got[i] != tt.want[i] {
+				t.Errorf("%s[%d] = %v, want %v", tt.header, i, *got[i], tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToText derives a plain-text version of an HTML email. Links are kept as numbered
+// footnotes and images are replaced by their alt text.
+func HTMLToText(htm string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htm))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %v", err)
+	}
+	w := &textWriter{}
+	w.walk(doc)
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(w.buf.String()))
+	sb.WriteString("\n")
+	if len(w.links) > 0 {
+		sb.WriteString("\n")
+		for i, link := range w.links {
+			fmt.Fprintf(&sb, "[%d] %s\n", i+1, link)
+		}
+	}
+	return sb.String(), nil
+}
+
+type textWriter struct {
+	buf strings.Builder
+	// pending is the number of line breaks owed before the next text.
+	pending int
+	// space is true when a collapsed whitespace run is owed before the next text.
+	space bool
+	links []string
+}
+
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Table: true, atom.Tr: true, atom.Ul: true, atom.Ol: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Section: true, atom.Header: true, atom.Footer: true,
+}
+
+func (w *textWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+		switch n.DataAtom {
+		case atom.Head, atom.Style, atom.Script, atom.Title:
+			return
+		case atom.Br:
+			w.newline(1)
+			return
+		case atom.Hr:
+			w.newline(1)
+			w.write("----------")
+			w.newline(1)
+			return
+		case atom.Img:
+			w.image(n)
+			return
+		case atom.Li:
+			w.newline(1)
+			w.write("- ")
+		case atom.Td, atom.Th:
+			w.space = true
+		}
+		if blockElements[n.DataAtom] {
+			w.newline(2)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+	if n.Type == html.ElementNode {
+		if n.DataAtom == atom.A {
+			w.link(n)
+		}
+		if blockElements[n.DataAtom] {
+			w.newline(2)
+		}
+	}
+}
+
+func (w *textWriter) text(s string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			w.space = true
+		}
+		return
+	}
+	if s[0] == ' ' || s[0] == '\t' || s[0] == '\n' || s[0] == '\r' {
+		w.space = true
+	}
+	w.write(strings.Join(fields, " "))
+	last := s[len(s)-1]
+	w.space = last == ' ' || last == '\t' || last == '\n' || last == '\r'
+}
+
+// write emits s, first flushing any owed line breaks or space.
+func (w *textWriter) write(s string) {
+	if w.buf.Len() > 0 {
+		if w.pending > 0 {
+			w.buf.WriteString(strings.Repeat("\n", w.pending))
+		} else if w.space {
+			w.buf.WriteByte(' ')
+		}
+	}
+	w.pending = 0
+	w.space = false
+	w.buf.WriteString(s)
+}
+
+func (w *textWriter) newline(n int) {
+	if n > w.pending {
+		w.pending = n
+	}
+	w.space = false
+}
+
+func (w *textWriter) image(n *html.Node) {
+	alt := strings.TrimSpace(attr(n, "alt"))
+	// Inline images use their hashed CID file name as alt text, which means nothing to a reader.
+	if alt == "" || alt == strings.TrimPrefix(attr(n, "src"), "cid:") {
+		return
+	}
+	w.write("[" + alt + "]")
+}
+
+func (w *textWriter) link(n *html.Node) {
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") || href == strings.TrimSpace(nodeText(n)) {
+		return
+	}
+	w.links = append(w.links, strings.TrimPrefix(href, "mailto:"))
+	w.space = true
+	w.write(fmt.Sprintf("[%d]", len(w.links)))
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
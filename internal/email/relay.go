@@ -0,0 +1,103 @@
+package email
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// RelayConfig describes one SMTP relay of an account. Relays with a lower Priority are tried first.
+type RelayConfig struct {
+	Name     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Priority int
+	// PoolSize is the maximum number of concurrent connections to the relay. Defaults to the account's PoolSize.
+	PoolSize int
+	// TLS defaults to the account's TLS settings when its Mode is empty.
+	TLS TLSConfig
+	// Auth defaults to the account's auth settings when its Mechanism is empty.
+	Auth AuthConfig
+}
+
+const (
+	// relayCooldown is how long a relay is skipped after its first failure. It doubles on every
+	// consecutive failure up to relayMaxCooldown.
+	relayCooldown    = 30 * time.Second
+	relayMaxCooldown = 5 * time.Minute
+)
+
+// RelayHealth is a snapshot of a relay's recent delivery results.
+type RelayHealth struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Priority int    `json:"priority"`
+	// Healthy is false while the relay is skipped after a connection or transient error.
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	// RetryAt is when an unhealthy relay is tried again.
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+	Sent    int64      `json:"sent"`
+	Failed  int64      `json:"failed"`
+}
+
+type relay struct {
+	config RelayConfig
+	pool   *connPool
+	health RelayHealth
+	mu     sync.Mutex
+}
+
+// available reports whether the relay is not cooling down after a failure.
+func (r *relay) available(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.health.RetryAt == nil || !now.Before(*r.health.RetryAt)
+}
+
+func (r *relay) succeeded() {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.health.Healthy {
+		log.Printf("SMTP 릴레이 %s 복구됨", r.config.Name)
+	}
+	r.health.Healthy = true
+	r.health.ConsecutiveFailures = 0
+	r.health.RetryAt = nil
+	r.health.LastSuccess = &now
+	r.health.Sent++
+}
+
+func (r *relay) failed(err error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health.Healthy = false
+	r.health.ConsecutiveFailures++
+	r.health.LastError = err.Error()
+	r.health.LastFailure = &now
+	r.health.Failed++
+	cooldown := relayCooldown
+	for i := 1; i < r.health.ConsecutiveFailures && cooldown < relayMaxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > relayMaxCooldown {
+		cooldown = relayMaxCooldown
+	}
+	retryAt := now.Add(cooldown)
+	r.health.RetryAt = &retryAt
+	log.Printf("SMTP 릴레이 %s 오류, %s까지 다른 릴레이를 사용합니다: %v", r.config.Name, retryAt.Format(time.RFC3339), err)
+}
+
+func (r *relay) snapshot() RelayHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.health
+}
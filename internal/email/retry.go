@@ -0,0 +1,101 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/textproto"
+	"time"
+)
+
+// SendError describes a failed delivery attempt.
+type SendError struct {
+	// Code is the SMTP reply code (or the HTTP status for HTTP transports), or 0 if the
+	// server never replied (e.g. a network error).
+	Code int
+	// Permanent is true when retrying the same message cannot succeed, such as an invalid mailbox.
+	Permanent bool
+	// Uncertain is true when the connection failed after the whole message was sent, so the
+	// server may have accepted it. Such a message must not be sent again, through this
+	// relay or any other, or the recipient could get it twice.
+	Uncertain bool
+	Err       error
+}
+
+func (e *SendError) Error() string {
+	kind := "transient"
+	if e.Permanent {
+		kind = "permanent"
+	} else if e.Uncertain {
+		kind = "uncertain"
+	}
+	return fmt.Sprintf("%s send error: %v", kind, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError wraps err in a SendError. SMTP 5xx replies are permanent and 4xx replies are
+// transient. Errors without an SMTP reply, such as network failures or an exhausted
+// connection pool, are treated as transient.
+func ClassifyError(err error) *SendError {
+	if err == nil {
+		return nil
+	}
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr
+	}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return &SendError{
+			Code:      protoErr.Code,
+			Permanent: protoErr.Code >= 500,
+			Err:       err,
+		}
+	}
+	return &SendError{Err: err}
+}
+
+// RetryPolicy controls how transient send errors are retried.
+type RetryPolicy struct {
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+// DefaultRetryPolicy is used for any field of a RetryPolicy that is left unset.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     6,
+	InitialInterval: 5 * time.Second,
+	MaxInterval:     5 * time.Minute,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = DefaultRetryPolicy.InitialInterval
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = DefaultRetryPolicy.MaxInterval
+	}
+	return p
+}
+
+// Backoff returns how long to wait after the given failed attempt (starting at 1).
+// The delay doubles on every attempt up to MaxInterval, and a random jitter of up to
+// half the delay keeps parallel workers from retrying in lockstep.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.InitialInterval
+	for i := 1; i < attempt && delay < p.MaxInterval; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxInterval {
+		delay = p.MaxInterval
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,41 @@
+package email
+
+// Sender delivers messages. SMTPClient is the production implementation; FileTransport
+// and MemoryTransport keep messages locally instead, for staging and tests.
+type Sender interface {
+	// SendEmail delivers the message and returns the server's response, such as the final
+	// SMTP reply. Failures should be a *SendError so callers can tell permanent rejections
+	// from transient ones; other errors are treated as transient.
+	SendEmail(msg *Message) (string, error)
+	// Encode returns the message exactly as SendEmail would deliver it, including any DKIM
+	// signature. Senders that do not deliver MIME return the equivalent MIME message.
+	Encode(msg *Message) (*Encoded, error)
+	// From returns the From address used for messages that do not set their own.
+	From() string
+	// Concurrency returns how many messages the sender can deliver at the same time.
+	Concurrency() int
+}
+
+// HealthReporter is implemented by senders that track the health of their relays.
+type HealthReporter interface {
+	Health() []RelayHealth
+}
+
+// Account is a named Sender together with the limits that apply to everything sent through it.
+type Account struct {
+	Name   string
+	Sender Sender
+	// Throttle limits how fast the account sends. Bulk senders call Throttle.Wait before each message.
+	Throttle *Throttle
+	Retry    RetryPolicy
+}
+
+// NewAccount creates an Account, filling in unset retry settings from DefaultRetryPolicy.
+func NewAccount(name string, sender Sender, limit RateLimit, retry RetryPolicy) *Account {
+	return &Account{
+		Name:     name,
+		Sender:   sender,
+		Throttle: NewThrottle(limit),
+		Retry:    retry.withDefaults(),
+	}
+}
@@ -1,11 +1,11 @@
 package email
 
 import (
+	"errors"
 	"fmt"
-	"net/smtp"
+	"log"
+	"sort"
 	"time"
-
-	"github.com/jordan-wright/email"
 )
 
 // SMTPConfig holds the configuration for the SMTP server.
@@ -15,36 +15,205 @@ type SMTPConfig struct {
 	Username string
 	Password string
 	From     string
+	// PoolSize is the maximum number of concurrent SMTP connections. Defaults to 10.
+	PoolSize int
+	// TLS applies to every relay that does not set its own TLS mode.
+	TLS TLSConfig
+	// Auth applies to every relay that does not set its own auth mechanism.
+	Auth AuthConfig
+	// DKIM signs every message of the account when its Domain is set.
+	DKIM DKIMConfig
+	// Relays are the SMTP servers the account fails over between. If empty, Host, Port,
+	// Username and Password form the only relay.
+	Relays []RelayConfig
 }
 
-// SMTPClient is a client for sending emails via SMTP using a third-party library.
+// SMTPClient is the Sender that delivers over pooled SMTP connections, failing over between relays.
 type SMTPClient struct {
 	Config SMTPConfig
-	Pool   *email.Pool
+	// DKIM is nil when signing is off.
+	DKIM *DKIMSigner
+	// relays are ordered by priority.
+	relays []*relay
+}
+
+const defaultPoolSize = 10
+
+// NewSMTPClient creates a new SMTPClient with the provided configuration. It does not connect;
+// use CheckRelays to verify the relays at startup.
+func NewSMTPClient(cfg SMTPConfig) (*SMTPClient, error) {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = defaultPoolSize
+	}
+	if len(cfg.Relays) == 0 {
+		cfg.Relays = []RelayConfig{{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}}
+	}
+	client := SMTPClient{Config: cfg}
+	if cfg.DKIM.Domain != "" {
+		signer, err := NewDKIMSigner(cfg.DKIM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DKIM settings: %w", err)
+		}
+		client.DKIM = signer
+	}
+	for _, rc := range cfg.Relays {
+		if rc.Name == "" {
+			rc.Name = fmt.Sprintf("%s:%d", rc.Host, rc.Port)
+		}
+		if rc.PoolSize <= 0 {
+			rc.PoolSize = cfg.PoolSize
+		}
+		if rc.TLS.Mode == "" {
+			rc.TLS = cfg.TLS
+		}
+		rc.TLS = rc.TLS.withDefaults(rc.Port)
+		if rc.Auth.Mechanism == "" {
+			rc.Auth = cfg.Auth
+		}
+		rc.Auth = rc.Auth.withDefaults(rc.Username)
+		auth, err := rc.Auth.build(rc.Username, rc.Password, rc.Host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth settings for relay %s: %w", rc.Name, err)
+		}
+		pool, err := newConnPool(rc.Name, rc.Host, rc.Port, rc.PoolSize, rc.TLS, auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS settings for relay %s: %w", rc.Name, err)
+		}
+		client.relays = append(client.relays, &relay{
+			config: rc,
+			pool:   pool,
+			health: RelayHealth{
+				Name:     rc.Name,
+				Host:     rc.Host,
+				Port:     rc.Port,
+				Priority: rc.Priority,
+				Healthy:  true,
+			},
+		})
+	}
+	sort.SliceStable(client.relays, func(a, b int) bool {
+		return client.relays[a].config.Priority < client.relays[b].config.Priority
+	})
+	return &client, nil
+}
+
+// CheckRelays connects and authenticates to every relay once. A relay that cannot satisfy
+// its TLS mode or rejects the credentials is a configuration error and is returned as a
+// *TLSError or *AuthError; a relay that is unreachable is logged and marked unhealthy so
+// sending fails over to the others.
+func (c *SMTPClient) CheckRelays() error {
+	for _, r := range c.relays {
+		err := r.pool.check()
+		var tlsErr *TLSError
+		var authErr *AuthError
+		if errors.As(err, &tlsErr) || errors.As(err, &authErr) {
+			return err
+		}
+		if err != nil {
+			log.Printf("SMTP 릴레이 %s 연결 확인 실패: %v", r.config.Name, err)
+			r.failed(err)
+		}
+	}
+	return nil
 }
 
-// NewSMTPClient creates a new SMTPClient with the provided configuration.
-func NewSMTPClient(cfg SMTPConfig) *SMTPClient {
-	client := SMTPClient{
-		Config: cfg,
+// From returns the account's default From address.
+func (c *SMTPClient) From() string {
+	return c.Config.From
+}
+
+// Concurrency returns the number of connections the client can use concurrently,
+// which is the pool size of its largest relay.
+func (c *SMTPClient) Concurrency() int {
+	size := 0
+	for _, r := range c.relays {
+		if r.config.PoolSize > size {
+			size = r.config.PoolSize
+		}
 	}
-	pool, err := email.NewPool(fmt.Sprintf("%s:%d", cfg.Host, cfg.Port), 10, smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host))
-	if err != nil {
-		panic(fmt.Errorf("failed to create email pool: %w", err))
+	return size
+}
+
+// Health returns the state of every relay in priority order.
+func (c *SMTPClient) Health() []RelayHealth {
+	health := make([]RelayHealth, 0, len(c.relays))
+	for _, r := range c.relays {
+		health = append(health, r.snapshot())
 	}
-	client.Pool = pool
-	return &client
+	return health
 }
 
-// SendEmail sends an email with the given recipients, subject, and HTML body using the "github.com/jordan-wright/email" library.
-func (c *SMTPClient) SendEmail(to []string, subject, body string, attachments []email.Attachment) error {
-	e := email.NewEmail()
-	e.From = c.Config.From
-	e.To = to
-	e.Subject = subject
-	e.HTML = []byte(body)
-	for _, attachment := range attachments {
-		e.Attachments = append(e.Attachments, &attachment)
+// candidates returns the relays to try for the next message: healthy relays in priority
+// order, followed by the relays that are cooling down in case every other relay fails too.
+func (c *SMTPClient) candidates() []*relay {
+	now := time.Now()
+	var healthy, cooling []*relay
+	for _, r := range c.relays {
+		if r.available(now) {
+			healthy = append(healthy, r)
+		} else {
+			cooling = append(cooling, r)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+// Encode serializes the message and signs it if DKIM is configured.
+func (c *SMTPClient) Encode(msg *Message) (*Encoded, error) {
+	return encodeSigned(msg, c.Config.From, c.DKIM)
+}
+
+// SendEmail sends the message, encoding it first unless msg.Encoded is set. Relays are
+// tried in priority order: connection and transient errors mark the relay unhealthy and move on
+// to the next one, while a permanent rejection is returned right away since another relay would
+// reject the message too. A relay that rejects the credentials is skipped as well, and when no
+// relay accepts them the failure is permanent, as retrying the message cannot fix it. Failures
+// are returned as a *SendError so callers can tell permanent rejections from transient ones.
+func (c *SMTPClient) SendEmail(msg *Message) (string, error) {
+	encoded := msg.Encoded
+	if encoded == nil {
+		var err error
+		if encoded, err = c.Encode(msg); err != nil {
+			return "", &SendError{Permanent: true, Err: err}
+		}
+	}
+
+	var sendErr *SendError
+	rejected := 0
+	candidates := c.candidates()
+	for _, r := range candidates {
+		resp, err := r.pool.send(encoded, 10*time.Second)
+		if err == nil {
+			r.succeeded()
+			return resp, nil
+		}
+		var authErr *AuthError
+		if errors.As(err, &authErr) {
+			// The relay cannot be used until its credentials are fixed, whatever the message.
+			r.failed(err)
+			rejected++
+			sendErr = &SendError{Permanent: rejected == len(candidates), Err: err}
+			continue
+		}
+		sendErr = ClassifyError(err)
+		if sendErr.Uncertain {
+			// The relay may have accepted the message, so it is not offered to another one.
+			r.failed(err)
+			return "", sendErr
+		}
+		if sendErr.Permanent {
+			// The relay answered, so it is working even though it refused this message.
+			return "", sendErr
+		}
+		// A timeout waiting for a free pooled connection means the relay is busy, not broken.
+		if !errors.Is(err, errPoolTimeout) {
+			r.failed(err)
+		}
 	}
-	return c.Pool.Send(e, 10*time.Second)
+	return "", sendErr
 }
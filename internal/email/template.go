@@ -4,35 +4,54 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/jordan-wright/email"
+	"github.com/vanng822/go-premailer/premailer"
 	"golang.org/x/crypto/sha3"
 	"html/template"
 	"net/textproto"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	texttemplate "text/template"
 )
 
 // TemplateManager manages the loading and rendering of HTML email templates.
 type TemplateManager struct {
 	templates map[string]*template.Template
-	baseDir   string
-	imageDir  string
-	mu        sync.RWMutex
+	// textTemplates holds the optional plain-text companions (name.txt next to name.html).
+	textTemplates map[string]*texttemplate.Template
+	// versions holds a content hash of each template and its text companion, see Version.
+	versions map[string]string
+	baseDir  string
+	imageDir string
+	mu       sync.RWMutex
 }
 
 func NewTemplateManager(baseDir string, imageDir string) *TemplateManager {
 	return &TemplateManager{
-		templates: make(map[string]*template.Template),
-		baseDir:   baseDir,
-		imageDir:  imageDir,
+		templates:     make(map[string]*template.Template),
+		textTemplates: make(map[string]*texttemplate.Template),
+		versions:      make(map[string]string),
+		baseDir:       baseDir,
+		imageDir:      imageDir,
 	}
 }
 
+// RenderedEmail is a template rendered for one recipient and ready to be sent.
+type RenderedEmail struct {
+	HTML        string
+	Text        string
+	Attachments []email.Attachment
+	// TemplateVersion identifies the template content the email was rendered from.
+	TemplateVersion string
+}
+
 func (tm *TemplateManager) BaseDir() string {
 	return tm.baseDir
 }
 
 // LoadTemplate loads a template file and caches it under the given name.
+// A .txt file with the same base name, if present, is loaded as its plain-text companion.
 func (tm *TemplateManager) LoadTemplate(name, filename string) error {
 	fullPath := filepath.Join(tm.baseDir, filename)
 	tmpl, err := template.New(filename).Funcs(template.FuncMap{
@@ -43,30 +62,95 @@ func (tm *TemplateManager) LoadTemplate(name, filename string) error {
 	if err != nil {
 		return fmt.Errorf("failed to parse template file %s: %v", fullPath, err)
 	}
+	source, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to read template file %s: %v", fullPath, err)
+	}
+	var textTmpl *texttemplate.Template
+	textPath := TextTemplatePath(fullPath)
+	if textSource, err := os.ReadFile(textPath); err == nil {
+		source = append(source, textSource...)
+		textTmpl, err = texttemplate.New(filepath.Base(textPath)).Funcs(texttemplate.FuncMap{
+			"image":         func(imageSrc string) string { return "" },
+			"imageWithSize": func(imageSrc, width, height string) string { return "" },
+			"property":      func(key string) string { return "" },
+		}).ParseFiles(textPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse text template file %s: %v", textPath, err)
+		}
+	}
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 	tm.templates[name] = tmpl
+	tm.versions[name] = hashString(string(source))[:12]
+	if textTmpl != nil {
+		tm.textTemplates[name] = textTmpl
+	} else {
+		delete(tm.textTemplates, name)
+	}
 	return nil
 }
 
+// TextTemplatePath returns the path of the plain-text companion of an HTML template file.
+func TextTemplatePath(htmlPath string) string {
+	return strings.TrimSuffix(htmlPath, filepath.Ext(htmlPath)) + ".txt"
+}
+
+// RenderReport lists the problems found while rendering a template for one recipient.
+// Such problems do not fail the render, so they would otherwise go unnoticed.
+type RenderReport struct {
+	MissingProperties []string `json:"missing_properties,omitempty"`
+	MissingImages     []string `json:"missing_images,omitempty"`
+}
+
+func (r *RenderReport) addProperty(key string) {
+	for _, k := range r.MissingProperties {
+		if k == key {
+			return
+		}
+	}
+	r.MissingProperties = append(r.MissingProperties, key)
+}
+
+func (r *RenderReport) addImage(src string) {
+	for _, s := range r.MissingImages {
+		if s == src {
+			return
+		}
+	}
+	r.MissingImages = append(r.MissingImages, src)
+}
+
 // RenderTemplate executes the cached template identified by name using the provided data.
 func (tm *TemplateManager) RenderTemplate(name string, data interface{}) (string, []email.Attachment, error) {
+	return tm.render(name, data, &RenderReport{})
+}
+
+func (tm *TemplateManager) render(name string, data interface{}, report *RenderReport) (string, []email.Attachment, error) {
 	tm.mu.RLock()
 	tmpl, exists := tm.templates[name]
 	tm.mu.RUnlock()
 	if !exists {
 		return "", nil, fmt.Errorf("template %s not found", name)
 	}
+	// Render a clone so concurrent renders never share the per-call helper functions below,
+	// and the cached template stays unexecuted and therefore cloneable.
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to clone template %s: %v", name, err)
+	}
 	var buf bytes.Buffer
 	var attachments []email.Attachment
 	tmpl = tmpl.Funcs(template.FuncMap{
 		"image": func(imageSrc string) template.HTML {
 			imagePath := filepath.Join(tm.imageDir, imageSrc)
 			if _, err := os.Stat(imagePath); err != nil {
+				report.addImage(imageSrc)
 				return template.HTML(fmt.Sprintf("Image not found: %s", imageSrc))
 			}
 			content, err := os.ReadFile(imagePath)
 			if err != nil {
+				report.addImage(imageSrc)
 				return template.HTML(fmt.Sprintf("Failed to read image: %s", imageSrc))
 			}
 			// Inline images must have unique names
@@ -89,10 +173,12 @@ func (tm *TemplateManager) RenderTemplate(name string, data interface{}) (string
 		"imageWithSize": func(imageSrc, width, height string) template.HTML {
 			imagePath := filepath.Join(tm.imageDir, imageSrc)
 			if _, err := os.Stat(imagePath); err != nil {
+				report.addImage(imageSrc)
 				return template.HTML(fmt.Sprintf("Image not found: %s", imageSrc))
 			}
 			content, err := os.ReadFile(imagePath)
 			if err != nil {
+				report.addImage(imageSrc)
 				return template.HTML(fmt.Sprintf("Failed to read image: %s", imageSrc))
 			}
 			// Inline images must have unique names
@@ -111,16 +197,7 @@ func (tm *TemplateManager) RenderTemplate(name string, data interface{}) (string
 			})
 			return template.HTML(fmt.Sprintf("<img src=\"cid:%s\" alt=\"%s\" width=\"%s\" height=\"%s\">", imageSrc, imageSrc, width, height))
 		},
-		"property": func(key string) string {
-			if dataMap, ok := data.(map[string]interface{}); ok {
-				if custom, ok := dataMap["custom"].(map[string]string); ok {
-					if val, exists := custom[key]; exists {
-						return val
-					}
-				}
-			}
-			return ""
-		},
+		"property": propertyFunc(data, report),
 	})
 
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -129,6 +206,85 @@ func (tm *TemplateManager) RenderTemplate(name string, data interface{}) (string
 	return buf.String(), attachments, nil
 }
 
+// propertyFunc returns the "property" template function, which looks up a recipient's custom property.
+func propertyFunc(data interface{}, report *RenderReport) func(key string) string {
+	return func(key string) string {
+		if dataMap, ok := data.(map[string]interface{}); ok {
+			if custom, ok := dataMap["custom"].(map[string]string); ok {
+				if val, exists := custom[key]; exists {
+					return val
+				}
+			}
+		}
+		report.addProperty(key)
+		return ""
+	}
+}
+
+// renderText executes the plain-text companion of the template, if there is one.
+func (tm *TemplateManager) renderText(name string, data interface{}, report *RenderReport) (string, bool, error) {
+	tm.mu.RLock()
+	tmpl, exists := tm.textTemplates[name]
+	tm.mu.RUnlock()
+	if !exists {
+		return "", false, nil
+	}
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to clone text template %s: %v", name, err)
+	}
+	tmpl = tmpl.Funcs(texttemplate.FuncMap{
+		"image":         func(imageSrc string) string { return "" },
+		"imageWithSize": func(imageSrc, width, height string) string { return "" },
+		"property":      propertyFunc(data, report),
+	})
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", false, fmt.Errorf("failed to execute text template %s: %v", name, err)
+	}
+	return buf.String(), true, nil
+}
+
+// RenderEmail renders the template, inlines its CSS with premailer and adds a plain-text
+// alternative, so the result is ready to be sent. The text comes from the template's .txt
+// companion if it has one, and is otherwise derived from the HTML.
+func (tm *TemplateManager) RenderEmail(name string, data interface{}) (*RenderedEmail, error) {
+	rendered, _, err := tm.RenderEmailWithReport(name, data)
+	return rendered, err
+}
+
+// RenderEmailWithReport is like RenderEmail but also reports properties and images that could not be resolved.
+func (tm *TemplateManager) RenderEmailWithReport(name string, data interface{}) (*RenderedEmail, *RenderReport, error) {
+	report := &RenderReport{}
+	body, attachments, err := tm.render(name, data, report)
+	if err != nil {
+		return nil, report, err
+	}
+	premail, err := premailer.NewPremailerFromString(body, premailer.NewOptions())
+	if err != nil {
+		return nil, report, fmt.Errorf("failed to create premailer for template %s: %v", name, err)
+	}
+	htm, err := premail.Transform()
+	if err != nil {
+		return nil, report, fmt.Errorf("failed to transform template %s with premailer: %v", name, err)
+	}
+	text, ok, err := tm.renderText(name, data, report)
+	if err != nil {
+		return nil, report, err
+	}
+	if !ok {
+		if text, err = HTMLToText(htm); err != nil {
+			return nil, report, fmt.Errorf("failed to derive text from template %s: %v", name, err)
+		}
+	}
+	return &RenderedEmail{
+		HTML:            htm,
+		Text:            text,
+		Attachments:     attachments,
+		TemplateVersion: tm.Version(name),
+	}, report, nil
+}
+
 func (tm *TemplateManager) ListTemplates() []string {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -153,6 +309,16 @@ func (tm *TemplateManager) DeleteTemplate(name string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 	delete(tm.templates, name)
+	delete(tm.textTemplates, name)
+	delete(tm.versions, name)
+}
+
+// Version returns a short hash of the loaded template's content, which changes whenever
+// the template or its text companion is edited.
+func (tm *TemplateManager) Version(name string) string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.versions[name]
 }
 
 func (tm *TemplateManager) Templates() map[string]*template.Template {
@@ -0,0 +1,180 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit holds the outbound message limits of a single SMTP account. Zero means unlimited.
+type RateLimit struct {
+	PerSecond int
+	PerMinute int
+	PerDay    int
+}
+
+type window struct {
+	size time.Duration
+	max  int
+}
+
+// Throttle enforces a RateLimit using a sliding window of recent send times.
+// A nil Throttle never blocks.
+type Throttle struct {
+	windows []window
+	longest time.Duration
+	sent    []time.Time
+	// log records every send time when the throttle is persisted, see Persist.
+	log *os.File
+	mu  sync.Mutex
+}
+
+// NewThrottle returns a Throttle for the given limits, or nil if no limit is set.
+func NewThrottle(limit RateLimit) *Throttle {
+	t := &Throttle{}
+	for _, w := range []window{
+		{size: time.Second, max: limit.PerSecond},
+		{size: time.Minute, max: limit.PerMinute},
+		{size: 24 * time.Hour, max: limit.PerDay},
+	} {
+		if w.max <= 0 {
+			continue
+		}
+		t.windows = append(t.windows, w)
+		if w.size > t.longest {
+			t.longest = w.size
+		}
+	}
+	if len(t.windows) == 0 {
+		return nil
+	}
+	return t
+}
+
+// Persist keeps the send times in the file at path so that the limits, notably PerDay, also
+// hold across restarts. It loads the sends of the last window from the file, rewrites it with
+// only those, and appends every later send. It does nothing on a nil Throttle.
+func (t *Throttle) Persist(path string) error {
+	if t == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create send log directory: %w", err)
+	}
+	var sent []time.Time
+	if f, err := os.Open(path); err == nil {
+		cutoff := time.Now().Add(-t.longest)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			// A line cut short by a crash is skipped.
+			nanos, err := strconv.ParseInt(scanner.Text(), 10, 64)
+			if err != nil {
+				continue
+			}
+			if at := time.Unix(0, nanos); at.After(cutoff) {
+				sent = append(sent, at)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read send log %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to open send log %s: %w", path, err)
+	}
+	sort.Slice(sent, func(a, b int) bool { return sent[a].Before(sent[b]) })
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite send log %s: %w", path, err)
+	}
+	w := bufio.NewWriter(f)
+	for _, at := range sent {
+		fmt.Fprintln(w, at.UnixNano())
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to rewrite send log %s: %w", path, err)
+	}
+	f.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rewrite send log %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open send log %s: %w", path, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = append(sent, t.sent...)
+	t.log = file
+	return nil
+}
+
+// Wait blocks until another message may be sent without exceeding the limits and reserves
+// a slot for it. When a window is exhausted the caller is deferred until the window frees up
+// instead of failing. It returns early with the context's error if ctx is cancelled.
+func (t *Throttle) Wait(ctx context.Context) error {
+	if t == nil {
+		return ctx.Err()
+	}
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		delay := t.delay(now)
+		if delay <= 0 {
+			t.sent = append(t.sent, now)
+			if t.log != nil {
+				if _, err := fmt.Fprintln(t.log, now.UnixNano()); err != nil {
+					log.Printf("발송 기록 저장 실패: %v", err)
+				}
+			}
+			t.mu.Unlock()
+			return nil
+		}
+		t.mu.Unlock()
+
+		if delay >= time.Minute {
+			log.Printf("SMTP 발송 한도 도달, %s까지 대기합니다.", now.Add(delay).Format(time.RFC3339))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// delay prunes expired send times and returns how long to wait before the next send.
+func (t *Throttle) delay(now time.Time) time.Duration {
+	expired := sort.Search(len(t.sent), func(i int) bool {
+		return t.sent[i].After(now.Add(-t.longest))
+	})
+	t.sent = t.sent[expired:]
+
+	var delay time.Duration
+	for _, w := range t.windows {
+		start := sort.Search(len(t.sent), func(i int) bool {
+			return t.sent[i].After(now.Add(-w.size))
+		})
+		if len(t.sent)-start < w.max {
+			continue
+		}
+		// The window is full; wait until its oldest send that keeps it full has expired.
+		if d := t.sent[len(t.sent)-w.max].Add(w.size).Sub(now); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
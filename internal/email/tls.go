@@ -0,0 +1,91 @@
+package email
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSMode selects how a relay connection is encrypted.
+type TLSMode string
+
+const (
+	// TLSOpportunistic upgrades with STARTTLS when the server offers it and sends in plain text otherwise.
+	TLSOpportunistic TLSMode = "opportunistic"
+	// TLSStartTLS requires the server to offer STARTTLS and refuses to send without it.
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit starts TLS right after connecting, as on port 465 (SMTPS).
+	TLSImplicit TLSMode = "implicit"
+	// TLSNone never encrypts, e.g. for a local relay on port 25.
+	TLSNone TLSMode = "none"
+)
+
+// TLSConfig controls the encryption of a relay connection.
+type TLSConfig struct {
+	// Mode defaults to TLSImplicit on port 465 and TLSOpportunistic on any other port.
+	Mode TLSMode
+	// CAFile is a PEM bundle trusted in addition to the system roots, e.g. for a private CA.
+	CAFile string
+	// ServerName is the name the server certificate must be valid for. Defaults to the relay host.
+	ServerName string
+}
+
+// TLSError reports that a relay cannot satisfy its configured TLS mode.
+type TLSError struct {
+	Relay string
+	Mode  TLSMode
+	Err   error
+}
+
+func (e *TLSError) Error() string {
+	return fmt.Sprintf("relay %s cannot satisfy TLS mode %s: %v", e.Relay, e.Mode, e.Err)
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// withDefaults fills in the mode for the given port.
+func (t TLSConfig) withDefaults(port int) TLSConfig {
+	if t.Mode == "" {
+		t.Mode = TLSOpportunistic
+		if port == 465 {
+			t.Mode = TLSImplicit
+		}
+	}
+	return t
+}
+
+// build returns the crypto/tls configuration for connecting to host, or nil for TLSNone.
+func (t TLSConfig) build(host string) (*tls.Config, error) {
+	switch t.Mode {
+	case TLSOpportunistic, TLSStartTLS, TLSImplicit:
+	case TLSNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q", t.Mode)
+	}
+	cfg := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if t.ServerName != "" {
+		cfg.ServerName = t.ServerName
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
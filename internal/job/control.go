@@ -0,0 +1,67 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// control lets the API pause, resume and cancel a job while its send loop is running.
+// The send loop checks it between recipients and between retry attempts.
+type control struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+func newControl() *control {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &control{ctx: ctx, cancel: cancel}
+}
+
+func (c *control) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resume = make(chan struct{})
+	}
+}
+
+func (c *control) unpause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resume)
+	}
+}
+
+// wait blocks while the job is paused and returns an error once it has been cancelled.
+func (c *control) wait() error {
+	c.mu.Lock()
+	paused, resume := c.paused, c.resume
+	c.mu.Unlock()
+	if paused {
+		select {
+		case <-resume:
+		case <-c.ctx.Done():
+		}
+	}
+	return c.ctx.Err()
+}
+
+// sleep waits for d unless the job is cancelled first.
+func (c *control) sleep(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
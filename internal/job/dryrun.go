@@ -0,0 +1,57 @@
+package job
+
+import (
+	"fmt"
+	"net/mail"
+
+	"mail-manager/internal/suppression"
+)
+
+// DryRunResult reports how the mail for one recipient would be rendered.
+type DryRunResult struct {
+	Email             string   `json:"email"`
+	Name              string   `json:"name"`
+	OK                bool     `json:"ok"`
+	Error             string   `json:"error,omitempty"`
+	MissingProperties []string `json:"missing_properties,omitempty"`
+	MissingImages     []string `json:"missing_images,omitempty"`
+	Size              int      `json:"size"` // Bytes of the rendered HTML, text and inline images
+	Attachments       int      `json:"attachments"`
+	// Suppressed is the reason the address is on the suppression list, if it is. Such a
+	// recipient is skipped when the job runs.
+	Suppressed suppression.Reason `json:"suppressed,omitempty"`
+}
+
+// DryRun renders the template for every recipient exactly like a send job would, without sending anything.
+// A recipient is only OK if its address is valid and every property and image resolved;
+// whether it is suppressed is reported separately.
+func (m *Manager) DryRun(template string, recipients []*Recipient) []DryRunResult {
+	results := make([]DryRunResult, 0, len(recipients))
+	for _, rec := range recipients {
+		result := DryRunResult{
+			Email: rec.Email,
+			Name:  rec.Name,
+		}
+		if entry, ok := m.suppressed(rec.Email); ok {
+			result.Suppressed = entry.Reason
+		}
+		rendered, report, err := m.TemplateManager.RenderEmailWithReport(template, rec.TemplateData())
+		result.MissingProperties = report.MissingProperties
+		result.MissingImages = report.MissingImages
+		if err != nil {
+			result.Error = err.Error()
+		} else if _, addrErr := mail.ParseAddress(rec.Email); addrErr != nil {
+			result.Error = fmt.Sprintf("invalid recipient address %q: %v", rec.Email, addrErr)
+		}
+		if rendered != nil {
+			result.Size = len(rendered.HTML) + len(rendered.Text)
+			for _, attachment := range rendered.Attachments {
+				result.Size += len(attachment.Content)
+			}
+			result.Attachments = len(rendered.Attachments)
+		}
+		result.OK = result.Error == "" && len(report.MissingProperties) == 0 && len(report.MissingImages) == 0
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,93 @@
+package job
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened in a progress event.
+type EventType string
+
+const (
+	EventRendered EventType = "rendered"
+	EventSent     EventType = "sent"
+	EventRetry    EventType = "retry"
+	EventFailed   EventType = "failed"
+	// EventUncertain reports a recipient whose mail may or may not have been delivered.
+	EventUncertain EventType = "uncertain"
+	// EventSuppressed reports a recipient that was not sent to because it is on the suppression list.
+	EventSuppressed EventType = "suppressed"
+	EventStatus     EventType = "status"
+	// EventSummary is the last event of a job and carries its final counts.
+	EventSummary EventType = "summary"
+)
+
+// Event is a progress update of a running job.
+type Event struct {
+	Type     EventType `json:"type"`
+	JobID    string    `json:"job_id"`
+	Email    string    `json:"email,omitempty"`
+	Attempt  int       `json:"attempt,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	SMTPCode int       `json:"smtp_code,omitempty"`
+	Status   Status    `json:"status,omitempty"`
+	Summary  *Summary  `json:"summary,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// eventBus fans job events out to subscribers. Slow subscribers miss events
+// rather than holding up the send loop.
+type eventBus struct {
+	subs map[string]map[chan Event]struct{}
+	mu   sync.Mutex
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[string]map[chan Event]struct{})}
+}
+
+func (b *eventBus) subscribe(id string) (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+	b.mu.Lock()
+	if b.subs[id] == nil {
+		b.subs[id] = make(map[chan Event]struct{})
+	}
+	b.subs[id][ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[id], ch)
+		if len(b.subs[id]) == 0 {
+			delete(b.subs, id)
+		}
+	}
+}
+
+func (b *eventBus) publish(e Event) {
+	e.Time = time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[e.JobID] {
+		select {
+		case ch <- e:
+		default:
+			if e.Type == EventSummary {
+				// Make room so subscribers always learn that the job has ended.
+				select {
+				case <-ch:
+				default:
+				}
+				select {
+				case ch <- e:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Subscribe returns a channel of progress events for the job and a function that ends the subscription.
+func (m *Manager) Subscribe(id string) (<-chan Event, func()) {
+	return m.events.subscribe(id)
+}
@@ -0,0 +1,266 @@
+package job
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"mail-manager/internal/email"
+)
+
+// Status describes the lifecycle state of a send job.
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed"
+	StatusCancelled Status = "cancelled"
+)
+
+// RecipientState describes the delivery state of a single recipient within a job.
+type RecipientState string
+
+const (
+	RecipientPending RecipientState = "pending"
+	RecipientSending RecipientState = "sending"
+	RecipientSent    RecipientState = "sent"
+	RecipientFailed  RecipientState = "failed"
+	// RecipientSkipped marks a recipient that was never sent to because the job was cancelled.
+	RecipientSkipped RecipientState = "skipped"
+	// RecipientUncertain marks a recipient whose send was interrupted by a restart or lost its
+	// connection after the message was sent. The mail may or may not have been delivered, so
+	// it is never sent again automatically.
+	RecipientUncertain RecipientState = "uncertain"
+	// RecipientBounced marks a recipient whose mail was accepted but later came back as a
+	// delivery failure report.
+	RecipientBounced RecipientState = "bounced"
+	// RecipientSuppressed marks a recipient that was not sent to because its address is on
+	// the suppression list.
+	RecipientSuppressed RecipientState = "suppressed"
+)
+
+// Recipient holds the per-recipient data and delivery state of a job.
+type Recipient struct {
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	Custom    map[string]string `json:"custom,omitempty"`
+	State     RecipientState    `json:"state"`
+	Attempts  int               `json:"attempts"`
+	LastError string            `json:"last_error,omitempty"`
+	SMTPCode  int               `json:"smtp_code,omitempty"`  // Reply code of the last failed attempt, if the server replied
+	RetriedIn string            `json:"retried_in,omitempty"` // Job a failed recipient was re-enqueued in
+	MessageID string            `json:"message_id,omitempty"` // Message-Id of the sent mail, used to match bounces
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// TemplateData returns the data the recipient's mail is rendered with.
+func (r *Recipient) TemplateData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":   r.Name,
+		"email":  r.Email,
+		"year":   time.Now().Year(),
+		"custom": r.Custom,
+	}
+}
+
+// Job is a durable record of a single POST /api/email request.
+type Job struct {
+	ID         string       `json:"id"`
+	ParentID   string       `json:"parent_id,omitempty"` // Job whose failed recipients this job retries
+	Template   string       `json:"template"`
+	Subject    string       `json:"subject"`
+	Requester  string       `json:"requester"`
+	Status     Status       `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	SendAt     *time.Time   `json:"send_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Recipients []*Recipient `json:"recipients"`
+	// IdempotencyKey is the client-supplied key that makes repeated submissions return this job.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Sender is the name of the sender identity the job sends as. Empty means the default From.
+	Sender string `json:"sender,omitempty"`
+	// Options are applied to the mail of every recipient.
+	Options Options `json:"options"`
+}
+
+// MaxRecipientsWithCopies is the largest job that may have CC or BCC addresses. Every
+// recipient gets an individually rendered mail that carries the job's CC and BCC, so each of
+// those addresses receives one copy per recipient and every recipient sees the CC list.
+const MaxRecipientsWithCopies = 20
+
+// Options holds the optional envelope settings of a job.
+type Options struct {
+	ReplyTo []string          `json:"reply_to,omitempty"`
+	Cc      []string          `json:"cc,omitempty"`
+	Bcc     []string          `json:"bcc,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Validate checks the addresses and headers of the options.
+func (o Options) Validate() error {
+	for _, list := range [][]string{o.ReplyTo, o.Cc, o.Bcc} {
+		if err := email.ValidateAddresses(list); err != nil {
+			return err
+		}
+	}
+	return email.ValidateHeaders(o.Headers)
+}
+
+// CheckCopies returns an error if the options add CC or BCC addresses to a job with more
+// than MaxRecipientsWithCopies recipients.
+func (o Options) CheckCopies(recipients int) error {
+	if (len(o.Cc) > 0 || len(o.Bcc) > 0) && recipients > MaxRecipientsWithCopies {
+		return fmt.Errorf("cc and bcc are sent with every recipient's mail and are limited to jobs of at most %d recipients", MaxRecipientsWithCopies)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the options.
+func (o Options) Clone() Options {
+	c := Options{
+		ReplyTo: append([]string(nil), o.ReplyTo...),
+		Cc:      append([]string(nil), o.Cc...),
+		Bcc:     append([]string(nil), o.Bcc...),
+	}
+	if o.Headers != nil {
+		c.Headers = make(map[string]string, len(o.Headers))
+		for k, v := range o.Headers {
+			c.Headers[k] = v
+		}
+	}
+	return c
+}
+
+// Message builds the mail for one recipient of the job from its rendered template.
+// from is the sender identity's From header, or empty for the account default.
+func (j *Job) Message(from, to string, rendered *email.RenderedEmail) *email.Message {
+	msg := email.NewMessage([]string{to}, j.Subject, rendered)
+	msg.From = from
+	msg.ReplyTo = j.Options.ReplyTo
+	msg.Cc = j.Options.Cc
+	msg.Bcc = j.Options.Bcc
+	msg.Headers = j.Options.Headers
+	return msg
+}
+
+// Summary is a condensed view of a job used for listings.
+type Summary struct {
+	ID         string                 `json:"id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Template   string                 `json:"template"`
+	Subject    string                 `json:"subject"`
+	Requester  string                 `json:"requester"`
+	Status     Status                 `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	SendAt     *time.Time             `json:"send_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Total      int                    `json:"total"`
+	Counts     map[RecipientState]int `json:"counts"`
+}
+
+// New creates a job for the given recipients. A job with a sendAt time is held
+// as scheduled until then, otherwise it is queued for immediate delivery.
+func New(template, subject, requester string, recipients []*Recipient, sendAt *time.Time) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, rec := range recipients {
+		rec.State = RecipientPending
+		rec.UpdatedAt = now
+	}
+	status := StatusQueued
+	if sendAt != nil {
+		status = StatusScheduled
+	}
+	return &Job{
+		ID:         id,
+		Template:   template,
+		Subject:    subject,
+		Requester:  requester,
+		Status:     status,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		SendAt:     sendAt,
+		Recipients: recipients,
+	}, nil
+}
+
+// Finished reports whether the job has reached a final status.
+func (j *Job) Finished() bool {
+	return j.Status == StatusCompleted || j.Status == StatusCancelled
+}
+
+// DeadLetters returns the recipients whose send ultimately failed and that have not been retried yet.
+func (j *Job) DeadLetters() []*Recipient {
+	var failed []*Recipient
+	for _, rec := range j.Recipients {
+		if rec.State == RecipientFailed && rec.RetriedIn == "" {
+			failed = append(failed, rec)
+		}
+	}
+	return failed
+}
+
+// Summary returns the per-state recipient counts of the job.
+func (j *Job) Summary() Summary {
+	counts := make(map[RecipientState]int)
+	for _, rec := range j.Recipients {
+		counts[rec.State]++
+	}
+	return Summary{
+		ID:         j.ID,
+		ParentID:   j.ParentID,
+		Template:   j.Template,
+		Subject:    j.Subject,
+		Requester:  j.Requester,
+		Status:     j.Status,
+		CreatedAt:  j.CreatedAt,
+		UpdatedAt:  j.UpdatedAt,
+		SendAt:     j.SendAt,
+		FinishedAt: j.FinishedAt,
+		Total:      len(j.Recipients),
+		Counts:     counts,
+	}
+}
+
+// Clone returns a deep copy of the job so it can be read without holding the store lock.
+func (j *Job) Clone() *Job {
+	c := *j
+	c.Options = j.Options.Clone()
+	if j.SendAt != nil {
+		t := *j.SendAt
+		c.SendAt = &t
+	}
+	if j.FinishedAt != nil {
+		t := *j.FinishedAt
+		c.FinishedAt = &t
+	}
+	c.Recipients = make([]*Recipient, len(j.Recipients))
+	for i, rec := range j.Recipients {
+		r := *rec
+		if rec.Custom != nil {
+			r.Custom = make(map[string]string, len(rec.Custom))
+			for k, v := range rec.Custom {
+				r.Custom[k] = v
+			}
+		}
+		c.Recipients[i] = &r
+	}
+	return &c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
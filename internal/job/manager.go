@@ -0,0 +1,707 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mail-manager/internal/archive"
+	"mail-manager/internal/email"
+	"mail-manager/internal/suppression"
+)
+
+// Manager creates send jobs and delivers them in the background.
+type Manager struct {
+	Store           *Store
+	TemplateManager *email.TemplateManager
+	// Account is the default account, used by jobs without a sender identity.
+	Account *email.Account
+	// Accounts holds every account by name, including email.DefaultAccount.
+	Accounts map[string]*email.Account
+	// Identities holds the sender identities jobs may send as, by name.
+	Identities map[string]email.Identity
+	// Workers is the number of recipients rendered and sent in parallel within a job.
+	Workers int
+	// IdempotencyWindow is how long an idempotency key keeps returning the job it created.
+	IdempotencyWindow time.Duration
+	// ArchiveMessages keeps the exact bytes of every sent message in the store.
+	ArchiveMessages bool
+	// Archive records every sent message for searching. Nil disables it.
+	Archive *archive.Store
+	// Suppressions holds the addresses that are never sent to. Bounced recipients are added
+	// to it. Nil disables it.
+	Suppressions *suppression.List
+
+	running map[string]*control
+	timers  map[string]*time.Timer
+	events  *eventBus
+	mu      sync.Mutex
+	idemMu  sync.Mutex
+}
+
+// NewManager creates a Manager that sends through the given default account.
+// If workers is not set, it defaults to the concurrency of the account's sender.
+func NewManager(store *Store, tm *email.TemplateManager, account *email.Account, workers int) *Manager {
+	if workers <= 0 {
+		workers = account.Sender.Concurrency()
+	}
+	return &Manager{
+		Store:           store,
+		TemplateManager: tm,
+		Account:         account,
+		Accounts:        map[string]*email.Account{email.DefaultAccount: account},
+		Identities:      make(map[string]email.Identity),
+		Workers:         workers,
+		running:         make(map[string]*control),
+		timers:          make(map[string]*time.Timer),
+		events:          newEventBus(),
+	}
+}
+
+// Submit persists the job and starts delivering it in the background,
+// or holds it until its send_at time if it is scheduled.
+func (m *Manager) Submit(j *Job) error {
+	if err := m.Store.Create(j); err != nil {
+		return err
+	}
+	if j.Status == StatusScheduled {
+		m.schedule(j)
+		return nil
+	}
+	m.start(j.ID)
+	return nil
+}
+
+// SubmitIdempotent submits the job unless the same requester already submitted a job with
+// the same idempotency key within the idempotency window. In that case the earlier job is
+// returned and created is false. Jobs without a key are always submitted.
+func (m *Manager) SubmitIdempotent(j *Job) (result *Job, created bool, err error) {
+	if j.IdempotencyKey == "" {
+		return j, true, m.Submit(j)
+	}
+	m.idemMu.Lock()
+	defer m.idemMu.Unlock()
+	since := time.Now().Add(-m.IdempotencyWindow)
+	for _, existing := range m.Store.List() {
+		if existing.IdempotencyKey == j.IdempotencyKey && existing.Requester == j.Requester && existing.CreatedAt.After(since) {
+			return existing, false, nil
+		}
+	}
+	if err := m.Submit(j); err != nil {
+		return nil, false, err
+	}
+	return j, true, nil
+}
+
+// Resume restarts every job that was queued or running when the server stopped.
+// Recipients already sent are skipped, and recipients caught in the middle of a send
+// are marked uncertain instead of being sent again, so nobody gets the same job's mail twice.
+// Paused jobs are cleaned up the same way but stay paused until resumed through the API,
+// and scheduled jobs are armed again.
+func (m *Manager) Resume() {
+	for _, j := range m.Store.List() {
+		if j.Status == StatusScheduled {
+			m.schedule(j)
+			continue
+		}
+		if j.Status != StatusQueued && j.Status != StatusRunning && j.Status != StatusPaused {
+			continue
+		}
+		_, err := m.Store.Update(j.ID, func(j *Job) error {
+			now := time.Now()
+			for _, rec := range j.Recipients {
+				if rec.State == RecipientSending {
+					rec.State = RecipientUncertain
+					rec.LastError = "발송 도중 서버가 중단되어 전달 여부를 확인할 수 없습니다."
+					rec.UpdatedAt = now
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("작업 재개 준비 실패 (%s): %v", j.ID, err)
+			continue
+		}
+		if j.Status == StatusPaused {
+			continue
+		}
+		log.Printf("중단된 작업 재개 (%s)", j.ID)
+		m.start(j.ID)
+	}
+}
+
+// CancelJob stops the job, or drops it before it fires if it is scheduled.
+// Recipients that have not been sent yet are marked skipped.
+func (m *Manager) CancelJob(id string) (*Job, error) {
+	// The status change and the running check happen under m.mu so that a run goroutine
+	// cannot finish and leave m.running in between; it finishes the job itself otherwise.
+	m.mu.Lock()
+	j, err := m.Store.Update(id, func(j *Job) error {
+		if j.Finished() {
+			return ErrInvalidState
+		}
+		j.Status = StatusCancelled
+		return nil
+	})
+	c, running := m.running[id]
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	m.unschedule(id)
+	m.events.publish(Event{Type: EventStatus, JobID: id, Status: j.Status})
+	if running {
+		c.cancel()
+		return j, nil
+	}
+	// Nothing is sending for this job, so skip its recipients right away.
+	return m.finish(id)
+}
+
+// PauseJob holds the job before its next recipient or retry attempt.
+func (m *Manager) PauseJob(id string) (*Job, error) {
+	j, err := m.Store.Update(id, func(j *Job) error {
+		if j.Status != StatusQueued && j.Status != StatusRunning {
+			return ErrInvalidState
+		}
+		j.Status = StatusPaused
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	if c, running := m.running[id]; running {
+		c.pause()
+	}
+	m.mu.Unlock()
+	m.events.publish(Event{Type: EventStatus, JobID: id, Status: j.Status})
+	return j, nil
+}
+
+// ResumeJob continues a paused job.
+func (m *Manager) ResumeJob(id string) (*Job, error) {
+	j, err := m.Store.Update(id, func(j *Job) error {
+		if j.Status != StatusPaused {
+			return ErrInvalidState
+		}
+		j.Status = StatusRunning
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	c, running := m.running[id]
+	if running {
+		c.unpause()
+	}
+	m.mu.Unlock()
+	if !running {
+		m.start(id)
+	}
+	m.events.publish(Event{Type: EventStatus, JobID: id, Status: j.Status})
+	return j, nil
+}
+
+// RetryFailed re-enqueues the dead letters of a finished job as a new job with the
+// same template, subject and custom properties. The original recipients are linked to
+// the new job so they cannot be retried twice.
+func (m *Manager) RetryFailed(id, requester string) (*Job, error) {
+	original, err := m.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := New(original.Template, original.Subject, requester, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	retry.ParentID = id
+	retry.Sender = original.Sender
+	retry.Options = original.Options
+	_, err = m.Store.Update(id, func(j *Job) error {
+		if !j.Finished() {
+			return ErrInvalidState
+		}
+		failed := j.DeadLetters()
+		if len(failed) == 0 {
+			return ErrNoDeadLetters
+		}
+		for _, rec := range failed {
+			rec.RetriedIn = retry.ID
+			retry.Recipients = append(retry.Recipients, &Recipient{
+				Name:      rec.Name,
+				Email:     rec.Email,
+				Custom:    rec.Custom,
+				State:     RecipientPending,
+				UpdatedAt: retry.CreatedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Submit(retry); err != nil {
+		// Release the dead letters again so the retry can be requested once more.
+		if _, rollbackErr := m.Store.Update(id, func(j *Job) error {
+			for _, rec := range j.Recipients {
+				if rec.RetriedIn == retry.ID {
+					rec.RetriedIn = ""
+				}
+			}
+			return nil
+		}); rollbackErr != nil {
+			log.Printf("재시도 작업 롤백 실패 (%s): %v", id, rollbackErr)
+		}
+		return nil, err
+	}
+	return retry, nil
+}
+
+// AllowedIdentities returns the sender identities the user may send as, sorted by name.
+func (m *Manager) AllowedIdentities(user string, groups []string) []email.Identity {
+	identities := make([]email.Identity, 0, len(m.Identities))
+	for _, identity := range m.Identities {
+		if identity.Allowed(user, groups) {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(a, b int) bool {
+		return identities[a].Name < identities[b].Name
+	})
+	return identities
+}
+
+// route returns the account and From header the job sends with.
+func (m *Manager) route(j *Job) (*email.Account, string, error) {
+	if j.Sender == "" {
+		return m.Account, "", nil
+	}
+	identity, ok := m.Identities[j.Sender]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown sender identity %q", j.Sender)
+	}
+	account, ok := m.Accounts[identity.Account]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown account %q", identity.Account)
+	}
+	return account, identity.From(), nil
+}
+
+// RenderMessage renders the mail of the unsaved job for the recipient and encodes it exactly
+// as the job's sender would deliver it, without sending anything.
+func (m *Manager) RenderMessage(j *Job, rec *Recipient) (*email.Encoded, error) {
+	account, from, err := m.route(j)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := m.TemplateManager.RenderEmail(j.Template, rec.TemplateData())
+	if err != nil {
+		return nil, err
+	}
+	return account.Sender.Encode(j.Message(from, rec.Email, rendered))
+}
+
+// TestSubjectPrefix is prepended to the subject of test sends.
+const TestSubjectPrefix = "[TEST] "
+
+// SendTest renders the mail of the unsaved job for the sample recipient exactly like a send
+// job would and delivers it to the given address only. Reply-To and extra headers are kept,
+// but CC and BCC are dropped so nobody else receives the test. No job is recorded. It returns
+// ErrSuppressed if the address is on the suppression list.
+func (m *Manager) SendTest(ctx context.Context, j *Job, sample *Recipient, to string) error {
+	if entry, ok := m.suppressed(to); ok {
+		return fmt.Errorf("%w (%s)", ErrSuppressed, entry.Reason)
+	}
+	account, from, err := m.route(j)
+	if err != nil {
+		return err
+	}
+	rendered, err := m.TemplateManager.RenderEmail(j.Template, sample.TemplateData())
+	if err != nil {
+		return err
+	}
+	if err := account.Throttle.Wait(ctx); err != nil {
+		return err
+	}
+	msg := email.NewMessage([]string{to}, TestSubjectPrefix+j.Subject, rendered)
+	msg.From = from
+	msg.ReplyTo = j.Options.ReplyTo
+	msg.Headers = j.Options.Headers
+	if msg.Encoded, err = account.Sender.Encode(msg); err != nil {
+		return err
+	}
+	response, err := account.Sender.SendEmail(msg)
+	if err != nil {
+		return err
+	}
+	m.record(j, to, msg, rendered, response)
+	return nil
+}
+
+// record adds a sent message to the archive, if enabled. Failures are only logged since the
+// message has already been delivered.
+func (m *Manager) record(j *Job, to string, msg *email.Message, rendered *email.RenderedEmail, response string) {
+	if m.Archive == nil {
+		return
+	}
+	entry := &archive.Entry{
+		JobID:           j.ID,
+		Recipient:       BareAddress(to),
+		Subject:         msg.Subject,
+		Template:        j.Template,
+		TemplateVersion: rendered.TemplateVersion,
+		HTML:            rendered.HTML,
+		Requester:       j.Requester,
+		Sender:          j.Sender,
+		From:            msg.Encoded.From,
+		MessageID:       msg.Encoded.MessageID(),
+		Response:        response,
+	}
+	if err := m.Archive.Add(entry); err != nil {
+		log.Printf("발송 기록 저장 실패 (%s): %v", to, err)
+	}
+}
+
+// start runs the job in the background unless it is already running.
+func (m *Manager) start(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, running := m.running[id]; running {
+		return
+	}
+	c := newControl()
+	m.running[id] = c
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.running, id)
+			m.mu.Unlock()
+			c.cancel()
+		}()
+		m.run(id, c)
+	}()
+}
+
+func (m *Manager) run(id string, c *control) {
+	j, err := m.Store.Update(id, func(j *Job) error {
+		// CancelJob may have landed between the caller's status change and start.
+		if j.Status == StatusCancelled || j.Finished() {
+			return ErrInvalidState
+		}
+		if j.Status == StatusQueued {
+			j.Status = StatusRunning
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInvalidState) {
+		// Nothing is sent. CancelJob only finishes the job itself when it found nothing
+		// running, so finish it here as well; a second finish changes nothing.
+		if _, err := m.finish(id); err != nil {
+			log.Printf("작업 상태 저장 실패 (%s): %v", id, err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("작업 시작 실패 (%s): %v", id, err)
+		return
+	}
+	m.events.publish(Event{Type: EventStatus, JobID: id, Status: j.Status})
+	account, from, err := m.route(j)
+	if err != nil {
+		// Without an account nothing can be sent; fail every pending recipient.
+		log.Printf("작업 발송 계정 확인 실패 (%s): %v", id, err)
+		for i, rec := range j.Recipients {
+			if rec.State == RecipientPending {
+				m.setRecipient(id, i, RecipientFailed, 0, err)
+			}
+		}
+		m.finish(id)
+		return
+	}
+	// Extra workers would only wait for a free connection of the account.
+	workers := m.Workers
+	if workers > account.Sender.Concurrency() {
+		workers = account.Sender.Concurrency()
+	}
+	pending := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				m.deliver(c, account, from, j, i)
+			}
+		}()
+	}
+	for i, rec := range j.Recipients {
+		if rec.State != RecipientPending {
+			continue
+		}
+		if err := c.wait(); err != nil {
+			break
+		}
+		select {
+		case pending <- i:
+		case <-c.ctx.Done():
+		}
+	}
+	close(pending)
+	wg.Wait()
+	j, err = m.finish(id)
+	if err != nil {
+		log.Printf("작업 상태 저장 실패 (%s): %v", id, err)
+		return
+	}
+	log.Printf("작업 종료 (%s): %s", id, j.Status)
+}
+
+// finish records the final status of the job and publishes its summary. A cancelled job
+// keeps its status and every recipient still pending is marked skipped. A job that has
+// already finished is returned unchanged.
+func (m *Manager) finish(id string) (*Job, error) {
+	done := false
+	j, err := m.Store.Update(id, func(j *Job) error {
+		if j.FinishedAt != nil {
+			done = true
+			return nil
+		}
+		now := time.Now()
+		if j.Status == StatusCancelled {
+			for _, rec := range j.Recipients {
+				if rec.State == RecipientPending {
+					rec.State = RecipientSkipped
+					rec.UpdatedAt = now
+				}
+			}
+		} else {
+			j.Status = StatusCompleted
+		}
+		j.FinishedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if done {
+		return j, nil
+	}
+	summary := j.Summary()
+	m.events.publish(Event{Type: EventSummary, JobID: id, Status: j.Status, Summary: &summary})
+	return j, nil
+}
+
+// deliver renders and sends the mail for the i-th recipient of the job, recording every attempt.
+// It gives up early, leaving the recipient pending, if the job is cancelled between attempts.
+func (m *Manager) deliver(c *control, account *email.Account, from string, j *Job, i int) {
+	rec := j.Recipients[i]
+	if entry, ok := m.suppressed(rec.Email); ok {
+		log.Printf("발송 제외 목록에 있는 주소입니다 (%s): %s", rec.Email, entry.Reason)
+		m.setRecipient(j.ID, i, RecipientSuppressed, 0, fmt.Errorf("address is suppressed (%s)", entry.Reason))
+		m.events.publish(Event{Type: EventSuppressed, JobID: j.ID, Email: rec.Email})
+		return
+	}
+	rendered, err := m.TemplateManager.RenderEmail(j.Template, rec.TemplateData())
+	if err != nil {
+		log.Printf("템플릿 렌더링 실패 (%s): %v", rec.Email, err)
+		m.setRecipient(j.ID, i, RecipientFailed, 0, err)
+		m.events.publish(Event{Type: EventFailed, JobID: j.ID, Email: rec.Email, Error: err.Error()})
+		return
+	}
+	msg := j.Message(from, rec.Email, rendered)
+	msg.Cc = m.withoutSuppressed(msg.Cc)
+	msg.Bcc = m.withoutSuppressed(msg.Bcc)
+	// Encode once so every attempt sends identical bytes and the archived copy matches what was sent.
+	if msg.Encoded, err = account.Sender.Encode(msg); err != nil {
+		log.Printf("메일 생성 실패 (%s): %v", rec.Email, err)
+		m.setRecipient(j.ID, i, RecipientFailed, 0, err)
+		m.events.publish(Event{Type: EventFailed, JobID: j.ID, Email: rec.Email, Error: err.Error()})
+		return
+	}
+	m.events.publish(Event{Type: EventRendered, JobID: j.ID, Email: rec.Email})
+	retry := account.Retry
+	for attempt := 1; ; attempt++ {
+		if err := c.wait(); err != nil {
+			return
+		}
+		if err := account.Throttle.Wait(c.ctx); err != nil {
+			return
+		}
+		// The job may have been paused while waiting for the rate limit.
+		if err := c.wait(); err != nil {
+			return
+		}
+		m.setRecipient(j.ID, i, RecipientSending, attempt, nil)
+		response, err := account.Sender.SendEmail(msg)
+		if err == nil {
+			log.Printf("이메일 발송 성공 (%s): %s", rec.Email, response)
+			m.record(j, rec.Email, msg, rendered, response)
+			if m.ArchiveMessages {
+				if err := m.Store.SaveMessage(j.ID, i, msg.Encoded.Data); err != nil {
+					log.Printf("발송 메일 보관 실패 (%s): %v", rec.Email, err)
+				}
+			}
+			m.setSent(j.ID, i, attempt, msg.Encoded.MessageID())
+			m.events.publish(Event{Type: EventSent, JobID: j.ID, Email: rec.Email, Attempt: attempt})
+			return
+		}
+		sendErr := email.ClassifyError(err)
+		if sendErr.Uncertain {
+			log.Printf("이메일 발송 결과 불확실, 재발송하지 않습니다 (%s): %v", rec.Email, sendErr)
+			m.setRecipient(j.ID, i, RecipientUncertain, attempt, sendErr)
+			m.events.publish(Event{Type: EventUncertain, JobID: j.ID, Email: rec.Email, Attempt: attempt, Error: sendErr.Error()})
+			return
+		}
+		if sendErr.Permanent || attempt >= retry.MaxAttempts {
+			log.Printf("이메일 발송 최종 실패 (%s, %d회 시도): %v", rec.Email, attempt, sendErr)
+			m.setRecipient(j.ID, i, RecipientFailed, attempt, sendErr)
+			m.events.publish(Event{Type: EventFailed, JobID: j.ID, Email: rec.Email, Attempt: attempt, Error: sendErr.Error(), SMTPCode: sendErr.Code})
+			return
+		}
+		delay := retry.Backoff(attempt)
+		log.Printf("이메일 발송 실패, %s 후 재시도 (%s): %v", delay.Round(time.Second), rec.Email, sendErr)
+		// The attempt was rejected, so the recipient is safe to retry after a restart.
+		m.setRecipient(j.ID, i, RecipientPending, attempt, sendErr)
+		m.events.publish(Event{Type: EventRetry, JobID: j.ID, Email: rec.Email, Attempt: attempt, Error: sendErr.Error(), SMTPCode: sendErr.Code})
+		if err := c.sleep(delay); err != nil {
+			return
+		}
+	}
+}
+
+func (m *Manager) setSent(id string, i int, attempts int, messageID string) {
+	_, err := m.Store.Update(id, func(j *Job) error {
+		if i >= len(j.Recipients) {
+			return fmt.Errorf("recipient index %d out of range", i)
+		}
+		rec := j.Recipients[i]
+		rec.State = RecipientSent
+		rec.Attempts = attempts
+		rec.MessageID = messageID
+		rec.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		log.Printf("수신자 상태 저장 실패 (%s, %d): %v", id, i, err)
+	}
+}
+
+// MarkBounced records a delivery failure report for the mail sent with the given Message-Id.
+// addr is the address that failed. If it is the job recipient, the recipient is marked
+// bounced; an address that only received a copy through CC or BCC is just suppressed.
+// A report for a recipient already marked bounced changes nothing. It returns ErrNotFound
+// when no sent mail has the Message-Id.
+func (m *Manager) MarkBounced(messageID, addr, detail string) error {
+	jobID, i, err := m.Store.FindMessage(messageID)
+	if err != nil {
+		return err
+	}
+	addr = BareAddress(addr)
+	seen := false
+	j, err := m.Store.Update(jobID, func(j *Job) error {
+		rec := j.Recipients[i]
+		if !strings.EqualFold(BareAddress(rec.Email), addr) && (containsAddress(j.Options.Cc, addr) || containsAddress(j.Options.Bcc, addr)) {
+			return nil
+		}
+		// Otherwise the report names the recipient itself, possibly under an alias it forwards to.
+		addr = BareAddress(rec.Email)
+		if rec.State == RecipientBounced {
+			seen = true
+			return nil
+		}
+		rec.State = RecipientBounced
+		rec.LastError = detail
+		rec.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if seen {
+		// A report read again must not re-add an address an admin has since removed.
+		return nil
+	}
+	log.Printf("반송 처리 (%s, %s): %s", jobID, addr, detail)
+	if m.Suppressions == nil {
+		return nil
+	}
+	_, _, err = m.Suppressions.Add(suppression.Entry{
+		Email:  addr,
+		Reason: suppression.ReasonBounce,
+		Detail: detail,
+		JobID:  j.ID,
+	})
+	return err
+}
+
+// suppressed returns the suppression list entry of the address, if it has one.
+func (m *Manager) suppressed(addr string) (suppression.Entry, bool) {
+	if m.Suppressions == nil {
+		return suppression.Entry{}, false
+	}
+	return m.Suppressions.Get(BareAddress(addr))
+}
+
+// BareAddress returns the address without its display name, or addr itself if it does not parse.
+func BareAddress(addr string) string {
+	if parsed, err := mail.ParseAddress(addr); err == nil {
+		return parsed.Address
+	}
+	return strings.TrimSpace(addr)
+}
+
+// withoutSuppressed returns the CC or BCC list without suppressed addresses.
+func (m *Manager) withoutSuppressed(list []string) []string {
+	var kept []string
+	for _, addr := range list {
+		if _, ok := m.suppressed(addr); ok {
+			log.Printf("발송 제외 목록에 있는 참조 주소를 제외합니다: %s", addr)
+			continue
+		}
+		kept = append(kept, addr)
+	}
+	return kept
+}
+
+// containsAddress reports whether the list holds the address, comparing the bare addresses.
+func containsAddress(list []string, addr string) bool {
+	for _, entry := range list {
+		if parsed, err := mail.ParseAddress(entry); err == nil && strings.EqualFold(parsed.Address, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) setRecipient(id string, i int, state RecipientState, attempts int, sendErr error) {
+	_, err := m.Store.Update(id, func(j *Job) error {
+		if i >= len(j.Recipients) {
+			return fmt.Errorf("recipient index %d out of range", i)
+		}
+		rec := j.Recipients[i]
+		rec.State = state
+		if attempts > 0 {
+			rec.Attempts = attempts
+		}
+		if sendErr != nil {
+			rec.LastError = sendErr.Error()
+			var smtpErr *email.SendError
+			if errors.As(sendErr, &smtpErr) {
+				rec.SMTPCode = smtpErr.Code
+			}
+		}
+		rec.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		log.Printf("수신자 상태 저장 실패 (%s, %d): %v", id, i, err)
+	}
+}
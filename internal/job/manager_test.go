@@ -0,0 +1,373 @@
+package job
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mail-manager/internal/email"
+	"mail-manager/internal/suppression"
+)
+
+// newTestManager returns a manager that delivers to a MemoryTransport.
+func newTestManager(t *testing.T) (*Manager, *email.MemoryTransport) {
+	t.Helper()
+	dir := t.TempDir()
+	tmplDir := filepath.Join(dir, "templates")
+	if err := os.MkdirAll(tmplDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmplDir, "welcome.html"), []byte(`<p>Hello {{.name}}</p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm := email.NewTemplateManager(tmplDir, filepath.Join(dir, "images"))
+	if err := tm.LoadTemplate("welcome", "welcome.html"); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(filepath.Join(dir, "jobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := email.NewMemoryTransport("noreply@example.com")
+	account := email.NewAccount(email.DefaultAccount, transport, email.RateLimit{}, email.RetryPolicy{})
+	return NewManager(store, tm, account, 1), transport
+}
+
+// newTestJob builds an unsaved job for the welcome template.
+func newTestJob(t *testing.T, addrs ...string) *Job {
+	t.Helper()
+	recipients := make([]*Recipient, 0, len(addrs))
+	for _, addr := range addrs {
+		recipients = append(recipients, &Recipient{Email: addr})
+	}
+	j, err := New("welcome", "Welcome", "admin@example.com", recipients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+// waitFinished waits until the job has finished.
+func waitFinished(t *testing.T, m *Manager, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, err := m.Store.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.Finished() {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not finish, status %s", id, j.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMarkBouncedSuppressesBareAddress(t *testing.T) {
+	m, _ := newTestManager(t)
+	list, err := suppression.NewList(filepath.Join(t.TempDir(), "suppression.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Suppressions = list
+	j := newTestJob(t, "Bob <bob@example.com>")
+	j.Options.Cc = []string{"Boss <Boss@example.com>"}
+	if err := m.Submit(j); err != nil {
+		t.Fatal(err)
+	}
+	j = waitFinished(t, m, j.ID)
+	messageID := j.Recipients[0].MessageID
+	if messageID == "" {
+		t.Fatal("sent recipient has no message id")
+	}
+
+	// A bounce for the CC address only suppresses it.
+	if err := m.MarkBounced(messageID, "boss@example.com", "5.1.1 user unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := list.Get("boss@example.com"); !ok {
+		t.Error("bounced CC address was not suppressed")
+	}
+	if j, _ = m.Store.Get(j.ID); j.Recipients[0].State != RecipientSent {
+		t.Errorf("CC bounce changed the recipient to %s", j.Recipients[0].State)
+	}
+
+	if err := m.MarkBounced(messageID, "BOB@example.com", "5.2.2 mailbox full"); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ = m.Store.Get(j.ID); j.Recipients[0].State != RecipientBounced {
+		t.Errorf("recipient state = %s, want bounced", j.Recipients[0].State)
+	}
+	entry, ok := list.Get("bob@example.com")
+	if !ok || entry.Email != "bob@example.com" || entry.JobID != j.ID {
+		t.Errorf("suppression entry = %+v, %v; want bob@example.com from job %s", entry, ok, j.ID)
+	}
+	if _, ok := m.suppressed(j.Recipients[0].Email); !ok {
+		t.Error("the recipient as posted is not found on the suppression list")
+	}
+
+	// Reading the report again does not re-add an address an admin removed.
+	if err := list.Remove("bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.MarkBounced(messageID, "bob@example.com", "5.2.2 mailbox full"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := list.Get("bob@example.com"); ok {
+		t.Error("a repeated bounce re-added a removed address")
+	}
+}
+
+func TestRunSendsNothingForCancelledJob(t *testing.T) {
+	m, transport := newTestManager(t)
+	j := newTestJob(t, "alice@example.com", "bob@example.com")
+	// The job was cancelled after it was queued but before its run started.
+	j.Status = StatusCancelled
+	if err := m.Store.Create(j); err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := m.Subscribe(j.ID)
+	defer unsubscribe()
+
+	m.start(j.ID)
+	// The job counts as finished once it is cancelled; wait for its run to end as well.
+	j = waitFinished(t, m, j.ID)
+	deadline := time.Now().Add(5 * time.Second)
+	for j.FinishedAt == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		j, _ = m.Store.Get(j.ID)
+	}
+	// CancelJob finishing the same job afterwards changes nothing.
+	if _, err := m.finish(j.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(transport.Messages()); n != 0 {
+		t.Errorf("sent %d messages for a cancelled job", n)
+	}
+	j, _ = m.Store.Get(j.ID)
+	if j.Status != StatusCancelled || j.FinishedAt == nil {
+		t.Errorf("status %s, finished at %v", j.Status, j.FinishedAt)
+	}
+	for _, rec := range j.Recipients {
+		if rec.State != RecipientSkipped {
+			t.Errorf("recipient %s is %s, want skipped", rec.Email, rec.State)
+		}
+	}
+	summaries := 0
+	for len(events) > 0 {
+		if e := <-events; e.Type == EventSummary {
+			summaries++
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("published %d summaries, want 1", summaries)
+	}
+}
+
+// gatedSender is a MemoryTransport whose sends wait for the gate, so tests can act on a
+// job while one of its messages is being sent.
+type gatedSender struct {
+	*email.MemoryTransport
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (s *gatedSender) SendEmail(msg *email.Message) (string, error) {
+	s.started <- struct{}{}
+	<-s.gate
+	return s.MemoryTransport.SendEmail(msg)
+}
+
+// gate replaces the manager's sender with a gatedSender.
+func gate(m *Manager, transport *email.MemoryTransport) *gatedSender {
+	s := &gatedSender{MemoryTransport: transport, started: make(chan struct{}, 16), gate: make(chan struct{})}
+	m.Account.Sender = s
+	return s
+}
+
+// waitStarted waits until the next send has started.
+func (s *gatedSender) waitStarted(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no send started")
+	}
+}
+
+func states(j *Job) string {
+	var s []string
+	for _, rec := range j.Recipients {
+		s = append(s, string(rec.State))
+	}
+	return strings.Join(s, ",")
+}
+
+func TestResumeMarksInterruptedSendsUncertain(t *testing.T) {
+	m, transport := newTestManager(t)
+	j := newTestJob(t, "sent@example.com", "sending@example.com", "pending@example.com")
+	// The server stopped while the second recipient was being sent.
+	j.Status = StatusRunning
+	j.Recipients[0].State = RecipientSent
+	j.Recipients[1].State = RecipientSending
+	if err := m.Store.Create(j); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Resume()
+	j = waitFinished(t, m, j.ID)
+	if got := states(j); got != "sent,uncertain,sent" {
+		t.Errorf("states = %s, want sent,uncertain,sent", got)
+	}
+	messages := transport.Messages()
+	if len(messages) != 1 || messages[0].Message.To[0] != "pending@example.com" {
+		t.Errorf("sent %d messages, want only the pending recipient", len(messages))
+	}
+
+	// A second restart sends nothing again.
+	m.Resume()
+	if n := len(transport.Messages()); n != 1 {
+		t.Errorf("sent %d messages after another restart, want 1", n)
+	}
+}
+
+func TestCancelJobSkipsRemainingRecipients(t *testing.T) {
+	m, transport := newTestManager(t)
+	s := gate(m, transport)
+	j := newTestJob(t, "a@example.com", "b@example.com", "c@example.com")
+	if err := m.Submit(j); err != nil {
+		t.Fatal(err)
+	}
+	s.waitStarted(t)
+	if _, err := m.CancelJob(j.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(s.gate)
+
+	// The job counts as finished once it is cancelled; wait for its run to end as well.
+	j = waitFinished(t, m, j.ID)
+	deadline := time.Now().Add(5 * time.Second)
+	for j.FinishedAt == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		j, _ = m.Store.Get(j.ID)
+	}
+	if j.Status != StatusCancelled {
+		t.Errorf("status = %s, want cancelled", j.Status)
+	}
+	// The message being sent when the job was cancelled still goes out.
+	if got := states(j); got != "sent,skipped,skipped" {
+		t.Errorf("states = %s, want sent,skipped,skipped", got)
+	}
+	if n := len(transport.Messages()); n != 1 {
+		t.Errorf("sent %d messages, want 1", n)
+	}
+	if _, err := m.CancelJob(j.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("cancelling again: err = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestPauseHoldsSendsUntilResumed(t *testing.T) {
+	m, transport := newTestManager(t)
+	s := gate(m, transport)
+	j := newTestJob(t, "a@example.com", "b@example.com", "c@example.com")
+	if err := m.Submit(j); err != nil {
+		t.Fatal(err)
+	}
+	s.waitStarted(t)
+	if _, err := m.PauseJob(j.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Let the send in progress finish; nothing else may start while paused.
+	s.gate <- struct{}{}
+	select {
+	case <-s.started:
+		t.Fatal("a send started while the job was paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+	j, _ = m.Store.Get(j.ID)
+	if j.Status != StatusPaused || states(j) != "sent,pending,pending" {
+		t.Errorf("paused job: status %s, states %s", j.Status, states(j))
+	}
+
+	if _, err := m.ResumeJob(j.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(s.gate)
+	j = waitFinished(t, m, j.ID)
+	if j.Status != StatusCompleted || states(j) != "sent,sent,sent" {
+		t.Errorf("resumed job: status %s, states %s", j.Status, states(j))
+	}
+	if n := len(transport.Messages()); n != 3 {
+		t.Errorf("sent %d messages, want 3", n)
+	}
+}
+
+func TestRetryFailedLinksRecipients(t *testing.T) {
+	m, _ := newTestManager(t)
+	j := newTestJob(t, "ok@example.com", "not an address")
+	if err := m.Submit(j); err != nil {
+		t.Fatal(err)
+	}
+	j = waitFinished(t, m, j.ID)
+	if got := states(j); got != "sent,failed" {
+		t.Fatalf("states = %s, want sent,failed", got)
+	}
+
+	retry, err := m.RetryFailed(j.ID, "retrier@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.ParentID != j.ID || retry.Requester != "retrier@example.com" || len(retry.Recipients) != 1 || retry.Recipients[0].Email != "not an address" {
+		t.Errorf("retry job = %+v", retry)
+	}
+	j, _ = m.Store.Get(j.ID)
+	if j.Recipients[1].RetriedIn != retry.ID || j.Recipients[0].RetriedIn != "" {
+		t.Errorf("RetriedIn = %q, %q; want only the failed recipient linked to %s", j.Recipients[0].RetriedIn, j.Recipients[1].RetriedIn, retry.ID)
+	}
+	if _, err := m.RetryFailed(j.ID, "retrier@example.com"); !errors.Is(err, ErrNoDeadLetters) {
+		t.Errorf("second retry: err = %v, want ErrNoDeadLetters", err)
+	}
+	waitFinished(t, m, retry.ID)
+}
+
+func TestSubmitIdempotentReturnsOriginalJob(t *testing.T) {
+	m, transport := newTestManager(t)
+	m.IdempotencyWindow = time.Hour
+	first := newTestJob(t, "a@example.com")
+	first.IdempotencyKey = "key-1"
+	result, created, err := m.SubmitIdempotent(first)
+	if err != nil || !created || result.ID != first.ID {
+		t.Fatalf("first submit: %v, created %v", err, created)
+	}
+
+	again := newTestJob(t, "a@example.com")
+	again.IdempotencyKey = "key-1"
+	result, created, err = m.SubmitIdempotent(again)
+	if err != nil || created || result.ID != first.ID {
+		t.Errorf("repeated submit: job %s, created %v, err %v; want job %s", result.ID, created, err, first.ID)
+	}
+
+	// The key is scoped to the requester.
+	other := newTestJob(t, "a@example.com")
+	other.IdempotencyKey = "key-1"
+	other.Requester = "someone-else@example.com"
+	if _, created, err := m.SubmitIdempotent(other); err != nil || !created {
+		t.Errorf("submit by another requester: created %v, err %v", created, err)
+	}
+
+	waitFinished(t, m, first.ID)
+	waitFinished(t, m, other.ID)
+	if _, err := m.Store.Get(again.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the repeated job was stored: %v", err)
+	}
+	if n := len(transport.Messages()); n != 2 {
+		t.Errorf("sent %d messages, want 2", n)
+	}
+}
@@ -0,0 +1,55 @@
+package job
+
+import (
+	"log"
+	"time"
+)
+
+// schedule arms a timer that queues the job at its send_at time.
+// Jobs whose time has already passed, e.g. while the server was down, fire immediately.
+func (m *Manager) schedule(j *Job) {
+	delay := time.Until(*j.SendAt)
+	if delay < 0 {
+		delay = 0
+	}
+	id := j.ID
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, exists := m.timers[id]; exists {
+		t.Stop()
+	}
+	m.timers[id] = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		delete(m.timers, id)
+		m.mu.Unlock()
+		m.fire(id)
+	})
+	log.Printf("예약 발송 등록 (%s): %s", id, j.SendAt.Format(time.RFC3339))
+}
+
+// unschedule stops the timer of a scheduled job, if any.
+func (m *Manager) unschedule(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, exists := m.timers[id]; exists {
+		t.Stop()
+		delete(m.timers, id)
+	}
+}
+
+// fire moves a scheduled job to the queue and starts delivering it.
+func (m *Manager) fire(id string) {
+	_, err := m.Store.Update(id, func(j *Job) error {
+		if j.Status != StatusScheduled {
+			return ErrInvalidState
+		}
+		j.Status = StatusQueued
+		return nil
+	})
+	if err != nil {
+		log.Printf("예약 발송 시작 실패 (%s): %v", id, err)
+		return
+	}
+	log.Printf("예약 발송 시작 (%s)", id)
+	m.start(id)
+}
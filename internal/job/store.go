@@ -0,0 +1,199 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a job with the requested ID does not exist.
+	ErrNotFound = errors.New("job not found")
+	// ErrInvalidState is returned when a job cannot make the requested status change.
+	ErrInvalidState = errors.New("job cannot change to the requested state")
+	// ErrNoDeadLetters is returned when retrying a job that has no failed recipients left.
+	ErrNoDeadLetters = errors.New("job has no failed recipients to retry")
+	// ErrSuppressed is returned when a test mail is addressed to a suppressed address.
+	ErrSuppressed = errors.New("address is suppressed")
+)
+
+// Store persists jobs as individual JSON files inside a directory.
+type Store struct {
+	dir  string
+	jobs map[string]*Job
+	mu   sync.RWMutex
+}
+
+// NewStore opens the job directory, creating it if needed, and loads every stored job.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job directory %s: %w", dir, err)
+	}
+	s := &Store{
+		dir:  dir,
+		jobs: make(map[string]*Job),
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job directory %s: %w", dir, err)
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job file %s: %w", file.Name(), err)
+		}
+		var j Job
+		if err := json.Unmarshal(data, &j); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal job file %s: %w", file.Name(), err)
+		}
+		s.jobs[j.ID] = &j
+	}
+	return s, nil
+}
+
+// Create stores a new job.
+func (s *Store) Create(j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[j.ID]; exists {
+		return fmt.Errorf("job %s already exists", j.ID)
+	}
+	if err := s.write(j); err != nil {
+		return err
+	}
+	s.jobs[j.ID] = j.Clone()
+	return nil
+}
+
+// Get returns a copy of the job with the given ID.
+func (s *Store) Get(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return j.Clone(), nil
+}
+
+// List returns copies of all jobs, newest first.
+func (s *Store) List() []*Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.Clone())
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].CreatedAt.After(jobs[b].CreatedAt)
+	})
+	return jobs
+}
+
+// Update applies fn to the stored job and persists the result.
+// If fn returns an error, the job is left untouched.
+func (s *Store) Update(id string, fn func(j *Job) error) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	j := current.Clone()
+	if err := fn(j); err != nil {
+		return nil, err
+	}
+	j.UpdatedAt = time.Now()
+	if err := s.write(j); err != nil {
+		return nil, err
+	}
+	s.jobs[id] = j
+	return j.Clone(), nil
+}
+
+// FindMessage returns the job ID and recipient index of the mail sent with the given Message-Id.
+func (s *Store) FindMessage(messageID string) (string, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, j := range s.jobs {
+		for i, rec := range j.Recipients {
+			if rec.MessageID != "" && rec.MessageID == messageID {
+				return j.ID, i, nil
+			}
+		}
+	}
+	return "", 0, ErrNotFound
+}
+
+// write atomically replaces the job file.
+func (s *Store) write(j *Job) error {
+	if j.ID == "" || strings.ContainsAny(j.ID, `/\.`) {
+		return fmt.Errorf("invalid job id %q", j.ID)
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job %s: %w", j.ID, err)
+	}
+	return writeAtomic(filepath.Join(s.dir, j.ID+".json"), data)
+}
+
+// SaveMessage archives the exact bytes sent to the i-th recipient of the job.
+func (s *Store) SaveMessage(id string, i int, data []byte) error {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return fmt.Errorf("invalid job id %q", id)
+	}
+	dir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create message archive of job %s: %w", id, err)
+	}
+	return writeAtomic(filepath.Join(dir, fmt.Sprintf("%d.eml", i)), data)
+}
+
+// Message returns the archived message of the i-th recipient of the job, or ErrNotFound.
+func (s *Store) Message(id string, i int) ([]byte, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id, fmt.Sprintf("%d.eml", i)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// writeAtomic replaces the file through a synced temp file so a crash never leaves a truncated file behind.
+func writeAtomic(path string, data []byte) error {
+	name := filepath.Base(path)
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", name, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to sync %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to close %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save %s: %w", name, err)
+	}
+	return nil
+}
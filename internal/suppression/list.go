@@ -0,0 +1,198 @@
+package suppression
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when an address is not on the list.
+var ErrNotFound = errors.New("address is not suppressed")
+
+// Reason tells why an address was suppressed.
+type Reason string
+
+const (
+	ReasonManual      Reason = "manual"
+	ReasonBounce      Reason = "bounce"
+	ReasonUnsubscribe Reason = "unsubscribe"
+)
+
+// Valid reports whether the reason is one of the known reasons.
+func (r Reason) Valid() bool {
+	return r == ReasonManual || r == ReasonBounce || r == ReasonUnsubscribe
+}
+
+// Entry is one suppressed address.
+type Entry struct {
+	Email  string `json:"email"`
+	Reason Reason `json:"reason"`
+	// Detail is a free-form note, such as the diagnostic of a bounce.
+	Detail string `json:"detail,omitempty"`
+	// JobID is the job whose message bounced, for bounce entries.
+	JobID     string    `json:"job_id,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// List is a set of addresses that must not be mailed, persisted as a single JSON file.
+// Addresses are compared case-insensitively.
+type List struct {
+	path    string
+	entries map[string]Entry
+	mu      sync.RWMutex
+}
+
+// NewList opens the list stored at path, starting empty if the file does not exist yet.
+func NewList(path string) (*List, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create suppression list directory: %w", err)
+	}
+	l := &List{path: path, entries: make(map[string]Entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppression list %s: %w", path, err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal suppression list %s: %w", path, err)
+	}
+	for _, e := range entries {
+		l.entries[normalize(e.Email)] = e
+	}
+	return l, nil
+}
+
+func normalize(addr string) string {
+	return strings.ToLower(strings.TrimSpace(addr))
+}
+
+// Add suppresses the address of the entry, with ReasonManual unless it has a reason. If the
+// address is already on the list, the existing entry is kept and returned with created set
+// to false.
+func (l *List) Add(e Entry) (result Entry, created bool, err error) {
+	key := normalize(e.Email)
+	if key == "" {
+		return Entry{}, false, errors.New("email address is required")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if existing, ok := l.entries[key]; ok {
+		return existing, false, nil
+	}
+	e.Email = key
+	if e.Reason == "" {
+		e.Reason = ReasonManual
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	l.entries[key] = e
+	if err := l.write(); err != nil {
+		delete(l.entries, key)
+		return Entry{}, false, err
+	}
+	return e, true, nil
+}
+
+// Get returns the entry of the address.
+func (l *List) Get(addr string) (Entry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	e, ok := l.entries[normalize(addr)]
+	return e, ok
+}
+
+// List returns every entry, newest first.
+func (l *List) List() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].CreatedAt.After(entries[b].CreatedAt)
+	})
+	return entries
+}
+
+// Update changes the reason and detail of the address's entry.
+func (l *List) Update(addr string, reason Reason, detail string) (Entry, error) {
+	key := normalize(addr)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old, ok := l.entries[key]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	e := old
+	e.Reason = reason
+	e.Detail = detail
+	l.entries[key] = e
+	if err := l.write(); err != nil {
+		l.entries[key] = old
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Remove takes the address off the list.
+func (l *List) Remove(addr string) error {
+	key := normalize(addr)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(l.entries, key)
+	if err := l.write(); err != nil {
+		l.entries[key] = e
+		return err
+	}
+	return nil
+}
+
+// write atomically replaces the list file. The caller must hold the lock.
+func (l *List) write() error {
+	entries := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Email < entries[b].Email
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal suppression list: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for suppression list: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save suppression list: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"mail-manager/internal/email"
+)
+
+// AccessList names the users (by email) and groups allowed to do something.
+// An empty list allows nobody.
+type AccessList struct {
+	Users  []string
+	Groups []string
+}
+
+// Allows reports whether the user with the given email and groups is on the list.
+func (l AccessList) Allows(user string, groups []string) bool {
+	if user != "" {
+		for _, u := range l.Users {
+			if strings.EqualFold(u, user) {
+				return true
+			}
+		}
+	}
+	for _, g := range l.Groups {
+		for _, ug := range groups {
+			if g == ug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requireAdmin reports whether the logged-in user is an admin, writing a 403 response if not.
+func (h *APIHandler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !h.Admins.Allows(h.sessionEmail(r), h.sessionGroups(r)) {
+		http.Error(w, "관리자만 사용할 수 있습니다.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// SMTPHealthHandler reports the health of every SMTP relay, grouped by account.
+func (h *APIHandler) SMTPHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	names := make([]string, 0, len(h.JobManager.Accounts))
+	for name := range h.JobManager.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	accounts := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		reporter, ok := h.JobManager.Accounts[name].Sender.(email.HealthReporter)
+		if !ok {
+			// Local transports have no relays to report on.
+			continue
+		}
+		relays := reporter.Health()
+		healthy := 0
+		for _, relay := range relays {
+			if relay.Healthy {
+				healthy++
+			}
+		}
+		accounts = append(accounts, map[string]interface{}{
+			"name":    name,
+			"healthy": healthy > 0,
+			"relays":  relays,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"accounts": accounts,
+	})
+}
+
+// DKIMHandler shows the DNS TXT record each DKIM-signing account expects to be published.
+func (h *APIHandler) DKIMHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	names := make([]string, 0, len(h.JobManager.Accounts))
+	for name := range h.JobManager.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	records := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		var signer *email.DKIMSigner
+		switch sender := h.JobManager.Accounts[name].Sender.(type) {
+		case *email.SMTPClient:
+			signer = sender.DKIM
+		case *email.FileTransport:
+			signer = sender.DKIM
+		}
+		if signer == nil {
+			continue
+		}
+		value, err := signer.DNSRecord()
+		if err != nil {
+			http.Error(w, "DKIM 레코드 생성에 실패하였습니다.", http.StatusInternalServerError)
+			log.Printf("DKIMHandler 오류 (%s): %v", name, err)
+			return
+		}
+		records = append(records, map[string]interface{}{
+			"account":  name,
+			"domain":   signer.Domain,
+			"selector": signer.Selector,
+			"name":     signer.DNSName(),
+			"type":     "TXT",
+			"value":    value,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"records": records,
+	})
+}
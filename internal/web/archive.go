@@ -0,0 +1,111 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mail-manager/internal/archive"
+)
+
+// ArchiveHandler searches the sent message archive. Supported query parameters are
+// recipient, template, subject, from and to (RFC 3339 times or YYYY-MM-DD dates, where a
+// "to" date includes the whole day), limit (default 50) and offset.
+func (h *APIHandler) ArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.JobManager.Archive == nil {
+		http.Error(w, "발송 기록 보관이 설정되지 않았습니다.", http.StatusNotFound)
+		return
+	}
+	params := r.URL.Query()
+	q := archive.Query{
+		Recipient: strings.TrimSpace(params.Get("recipient")),
+		Template:  params.Get("template"),
+		Subject:   params.Get("subject"),
+		Limit:     50,
+	}
+	var err error
+	if q.Since, err = parseArchiveTime(params.Get("from"), false); err != nil {
+		http.Error(w, fmt.Sprintf("잘못된 시작 날짜입니다: %s", params.Get("from")), http.StatusBadRequest)
+		return
+	}
+	if q.Until, err = parseArchiveTime(params.Get("to"), true); err != nil {
+		http.Error(w, fmt.Sprintf("잘못된 종료 날짜입니다: %s", params.Get("to")), http.StatusBadRequest)
+		return
+	}
+	for name, target := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("잘못된 %s 값입니다: %s", name, value), http.StatusBadRequest)
+			return
+		}
+		*target = n
+	}
+
+	entries, total := h.JobManager.Archive.Search(q)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": entries,
+		"total":    total,
+	})
+}
+
+// parseArchiveTime parses an RFC 3339 time or a date. A date given as an end bound is
+// moved to the start of the next day so that the whole day is included.
+func parseArchiveTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// ArchiveEntryHandler returns one archived message, including its rendered HTML.
+func (h *APIHandler) ArchiveEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.JobManager.Archive == nil {
+		http.Error(w, "발송 기록 보관이 설정되지 않았습니다.", http.StatusNotFound)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/archive/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "유효한 기록 ID가 제공되지 않았습니다.", http.StatusBadRequest)
+		return
+	}
+	entry, err := h.JobManager.Archive.Get(id)
+	if errors.Is(err, archive.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("발송 기록을 찾을 수 없습니다: %s", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "발송 기록을 읽지 못하였습니다.", http.StatusInternalServerError)
+		log.Printf("ArchiveEntryHandler 오류 (%s): %v", id, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
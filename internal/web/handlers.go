@@ -3,6 +3,7 @@ package web
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"html/template"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"mime"
 	"net/http"
+	"net/mail"
 	"os"
 	"path"
 	"path/filepath"
@@ -19,26 +21,100 @@ import (
 	"github.com/vanng822/go-premailer/premailer"
 	"mail-manager/internal/auth"
 	"mail-manager/internal/email"
+	"mail-manager/internal/job"
 )
 
 type APIHandler struct {
 	OIDCService     *auth.OIDCService
 	TemplateManager *email.TemplateManager
-	SMTPClient      *email.SMTPClient
+	Sender          email.Sender
 	AuthentikClient *auth.AuthentikClient
+	JobManager      *job.Manager
 	ImageDir        string
+	// Admins may use the /api/admin endpoints.
+	Admins AccessList
+	// CurrentUser returns the email address and groups of the logged-in user. When nil,
+	// they are read from the OIDC session.
+	CurrentUser func(r *http.Request) (email string, groups []string)
 }
 
-func NewAPIHandler(oidc *auth.OIDCService, tm *email.TemplateManager, smtp *email.SMTPClient, authClient *auth.AuthentikClient, imageDir string) *APIHandler {
+func NewAPIHandler(oidc *auth.OIDCService, tm *email.TemplateManager, sender email.Sender, authClient *auth.AuthentikClient, jobs *job.Manager, imageDir string) *APIHandler {
 	return &APIHandler{
 		OIDCService:     oidc,
 		TemplateManager: tm,
-		SMTPClient:      smtp,
+		Sender:          sender,
 		AuthentikClient: authClient,
+		JobManager:      jobs,
 		ImageDir:        imageDir,
 	}
 }
 
+// sessionEmail returns the email address of the logged-in user, or an empty string if unknown.
+func (h *APIHandler) sessionEmail(r *http.Request) string {
+	emailAddress, _ := h.currentUser(r)
+	return emailAddress
+}
+
+// sessionGroups returns the group names of the logged-in user from the ID token.
+func (h *APIHandler) sessionGroups(r *http.Request) []string {
+	_, groups := h.currentUser(r)
+	return groups
+}
+
+func (h *APIHandler) currentUser(r *http.Request) (string, []string) {
+	if h.CurrentUser != nil {
+		return h.CurrentUser(r)
+	}
+	session, err := h.OIDCService.Store.Get(r, "oidc-session")
+	if err != nil {
+		return "", nil
+	}
+	emailAddress, _ := session.Values["email"].(string)
+	groups, _ := session.Values["groups"].([]string)
+	return emailAddress, groups
+}
+
+// checkSender reports whether the logged-in user may send as the named sender identity,
+// writing an error response if not. An empty name selects the default From address.
+func (h *APIHandler) checkSender(w http.ResponseWriter, r *http.Request, name string) bool {
+	if name == "" {
+		return true
+	}
+	identity, ok := h.JobManager.Identities[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("존재하지 않는 발신자입니다: %s", name), http.StatusBadRequest)
+		return false
+	}
+	if !identity.Allowed(h.sessionEmail(r), h.sessionGroups(r)) {
+		http.Error(w, fmt.Sprintf("발신자 %s(으)로 발송할 권한이 없습니다.", name), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// SendersHandler lists the sender identities the logged-in user may send as.
+func (h *APIHandler) SendersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	identities := h.JobManager.AllowedIdentities(h.sessionEmail(r), h.sessionGroups(r))
+	senders := make([]map[string]interface{}, 0, len(identities))
+	for _, identity := range identities {
+		senders = append(senders, map[string]interface{}{
+			"name":         identity.Name,
+			"display_name": identity.DisplayName,
+			"address":      identity.Address,
+			"from":         identity.From(),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"default": h.Sender.From(),
+		"senders": senders,
+	})
+}
+
 func (h *APIHandler) MeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
@@ -155,6 +231,9 @@ func (h *APIHandler) TemplateHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("템플릿 삭제에 실패하였습니다: %v", err), http.StatusInternalServerError)
 			return
 		}
+		if err := os.Remove(email.TextTemplatePath(filePath)); err != nil && !os.IsNotExist(err) {
+			log.Printf("템플릿 %s 텍스트 본문 삭제 오류: %v", tmplName, err)
+		}
 		h.TemplateManager.DeleteTemplate(tmplName)
 		response := map[string]interface{}{
 			"message": "템플릿이 삭제되었습니다.",
@@ -254,22 +333,31 @@ func (h *APIHandler) UsersHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(users)
 }
 
+// RecipientInfo is a recipient as posted by the frontend.
+type RecipientInfo struct {
+	Name   string            `json:"name"`
+	Email  string            `json:"email"`
+	Custom map[string]string `json:"custom,omitempty"`
+}
+
 func (h *APIHandler) EmailHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
 		return
 	}
 
-	type RecipientInfo struct {
-		Name   string            `json:"name"`
-		Email  string            `json:"email"`
-		Custom map[string]string `json:"custom,omitempty"`
-	}
-
 	var reqData struct {
 		Template  string          `json:"template"`
 		Subject   string          `json:"subject"`
 		Recipient []RecipientInfo `json:"recipient"`
+		SendAt    *time.Time      `json:"send_at,omitempty"`
+		// 발신자 이름, 비어 있으면 기본 발신 주소(smtp.from)를 사용합니다.
+		Sender string `json:"sender,omitempty"`
+		// Idempotency-Key 헤더 대신 사용할 수 있습니다.
+		IdempotencyKey string `json:"idempotency_key,omitempty"`
+		// true이면 모든 수신자에 대해 렌더링만 해 보고 실제로 발송하지 않습니다.
+		DryRun bool `json:"dry_run,omitempty"`
+		job.Options
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
@@ -281,47 +369,459 @@ func (h *APIHandler) EmailHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "필수 필드가 누락되었습니다.", http.StatusBadRequest)
 		return
 	}
-	go func(recipients []RecipientInfo) {
-		for _, rec := range recipients {
-			data := map[string]interface{}{
-				"name":   rec.Name,
-				"email":  rec.Email,
-				"year":   time.Now().Year(),
-				"custom": rec.Custom,
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = reqData.IdempotencyKey
+	}
+	if len(idempotencyKey) > 255 {
+		http.Error(w, "Idempotency-Key는 255자를 넘을 수 없습니다.", http.StatusBadRequest)
+		return
+	}
+	if err := reqData.Options.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("잘못된 발송 옵션입니다: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := reqData.Options.CheckCopies(len(reqData.Recipient)); err != nil {
+		http.Error(w, fmt.Sprintf("참조(cc)와 숨은 참조(bcc)는 수신자마다 함께 발송되므로 수신자 %d명 이하의 작업에서만 사용할 수 있습니다.", job.MaxRecipientsWithCopies), http.StatusBadRequest)
+		return
+	}
+	if reqData.SendAt != nil && !reqData.SendAt.After(time.Now()) {
+		http.Error(w, "예약 발송 시각(send_at)은 현재 시각 이후여야 합니다.", http.StatusBadRequest)
+		return
+	}
+	if !h.checkSender(w, r, reqData.Sender) {
+		return
+	}
+
+	recipients := make([]*job.Recipient, 0, len(reqData.Recipient))
+	for _, rec := range reqData.Recipient {
+		recipient := &job.Recipient{
+			Name:   rec.Name,
+			Email:  rec.Email,
+			Custom: rec.Custom,
+		}
+		// "이름 <주소>" 형식도 받지만 작업에는 주소만 저장합니다. 미리보기는 잘못된 주소도 결과에 표시합니다.
+		if addr, err := mail.ParseAddress(rec.Email); err == nil {
+			recipient.Email = addr.Address
+			if recipient.Name == "" {
+				recipient.Name = addr.Name
 			}
-			body, attachments, err := h.TemplateManager.RenderTemplate(reqData.Template, data)
-			if err != nil {
-				log.Printf("템플릿 렌더링 실패 (%s): %v", rec.Email, err)
-				continue
+		} else if !reqData.DryRun {
+			http.Error(w, fmt.Sprintf("올바르지 않은 수신자 주소입니다: %s", rec.Email), http.StatusBadRequest)
+			return
+		}
+		recipients = append(recipients, recipient)
+	}
+	if reqData.DryRun {
+		results := h.JobManager.DryRun(reqData.Template, recipients)
+		failed, suppressed := 0, 0
+		for _, result := range results {
+			if !result.OK {
+				failed++
 			}
-			premail, err := premailer.NewPremailerFromString(body, premailer.NewOptions())
-			if err != nil {
-				log.Printf("프리메일러 생성 실패 (%s): %v", rec.Email, err)
-				continue
+			if result.Suppressed != "" {
+				suppressed++
 			}
-			htm, err := premail.Transform()
-			if err != nil {
-				log.Printf("프리메일러 변환 실패 (%s): %v", rec.Email, err)
+		}
+		message := fmt.Sprintf("총 %d명 중 %d명의 메일에서 문제가 발견되었습니다.", len(results), failed)
+		if suppressed > 0 {
+			message += fmt.Sprintf(" %d명은 발송 제외 목록에 있어 발송되지 않습니다.", suppressed)
+		}
+		response := map[string]interface{}{
+			"message":    message,
+			"total":      len(results),
+			"failed":     failed,
+			"suppressed": suppressed,
+			"recipients": results,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	j, err := job.New(reqData.Template, reqData.Subject, h.sessionEmail(r), recipients, reqData.SendAt)
+	if err != nil {
+		http.Error(w, "발송 작업 생성에 실패하였습니다.", http.StatusInternalServerError)
+		log.Printf("EmailHandler 작업 생성 오류: %v", err)
+		return
+	}
+	j.IdempotencyKey = idempotencyKey
+	j.Sender = reqData.Sender
+	j.Options = reqData.Options
+	j, created, err := h.JobManager.SubmitIdempotent(j)
+	if err != nil {
+		http.Error(w, "발송 작업 저장에 실패하였습니다.", http.StatusInternalServerError)
+		log.Printf("EmailHandler 작업 저장 오류: %v", err)
+		return
+	}
+	if !created {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "이미 접수된 발송 요청입니다.",
+			"job_id":  j.ID,
+			"summary": j.Summary(),
+		})
+		return
+	}
+
+	message := fmt.Sprintf("총 %d명의 수신자에게 이메일을 발송합니다.", len(reqData.Recipient))
+	if j.SendAt != nil {
+		message = fmt.Sprintf("총 %d명의 수신자에게 %s에 이메일을 발송하도록 예약하였습니다.", len(reqData.Recipient), j.SendAt.Format(time.RFC3339))
+	}
+	response := map[string]interface{}{
+		"message": message,
+		"job_id":  j.ID,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// EmailTestHandler renders the mail for one sample recipient exactly like a bulk send
+// and delivers it only to the logged-in user, with a "[TEST]" subject prefix.
+func (h *APIHandler) EmailTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqData struct {
+		Template  string          `json:"template"`
+		Subject   string          `json:"subject"`
+		Recipient []RecipientInfo `json:"recipient"`
+		// 렌더링에 사용할 수신자 데이터, 없으면 recipient의 첫 번째 수신자를 사용합니다.
+		Sample *RecipientInfo `json:"sample,omitempty"`
+		Sender string         `json:"sender,omitempty"`
+		job.Options
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		http.Error(w, "올바르지 않은 JSON 페이로드입니다.", http.StatusBadRequest)
+		return
+	}
+	sample := reqData.Sample
+	if sample == nil && len(reqData.Recipient) > 0 {
+		sample = &reqData.Recipient[0]
+	}
+	if reqData.Template == "" || reqData.Subject == "" || sample == nil {
+		http.Error(w, "필수 필드가 누락되었습니다.", http.StatusBadRequest)
+		return
+	}
+	if err := reqData.Options.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("잘못된 발송 옵션입니다: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !h.checkSender(w, r, reqData.Sender) {
+		return
+	}
+	to := h.sessionEmail(r)
+	if to == "" {
+		http.Error(w, "로그인한 사용자의 이메일 주소를 확인할 수 없습니다.", http.StatusBadRequest)
+		return
+	}
+
+	rec := &job.Recipient{
+		Name:   sample.Name,
+		Email:  sample.Email,
+		Custom: sample.Custom,
+	}
+	test := &job.Job{
+		Template:  reqData.Template,
+		Subject:   reqData.Subject,
+		Requester: to,
+		Sender:    reqData.Sender,
+		Options:   reqData.Options,
+	}
+	err := h.JobManager.SendTest(r.Context(), test, rec, to)
+	if errors.Is(err, job.ErrSuppressed) {
+		http.Error(w, fmt.Sprintf("%s 주소는 발송 제외 목록에 있어 테스트 메일을 보낼 수 없습니다.", to), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("테스트 메일 발송에 실패하였습니다: %v", err), http.StatusBadGateway)
+		log.Printf("EmailTestHandler 오류 (%s): %v", to, err)
+		return
+	}
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("%s 님의 데이터로 렌더링한 테스트 메일을 %s 주소로 발송하였습니다.", sample.Name, to),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// EmailEMLHandler renders the template for one recipient into the full MIME message,
+// exactly as it would be sent, and returns it as a downloadable .eml file.
+func (h *APIHandler) EmailEMLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqData struct {
+		Template  string         `json:"template"`
+		Subject   string         `json:"subject"`
+		Recipient *RecipientInfo `json:"recipient"`
+		Sender    string         `json:"sender,omitempty"`
+		job.Options
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		http.Error(w, "올바르지 않은 JSON 페이로드입니다.", http.StatusBadRequest)
+		return
+	}
+	if reqData.Template == "" || reqData.Subject == "" || reqData.Recipient == nil || reqData.Recipient.Email == "" {
+		http.Error(w, "필수 필드가 누락되었습니다.", http.StatusBadRequest)
+		return
+	}
+	if err := reqData.Options.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("잘못된 발송 옵션입니다: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !h.checkSender(w, r, reqData.Sender) {
+		return
+	}
+
+	preview := &job.Job{
+		Template: reqData.Template,
+		Subject:  reqData.Subject,
+		Sender:   reqData.Sender,
+		Options:  reqData.Options,
+	}
+	rec := &job.Recipient{
+		Name:   reqData.Recipient.Name,
+		Email:  reqData.Recipient.Email,
+		Custom: reqData.Recipient.Custom,
+	}
+	encoded, err := h.JobManager.RenderMessage(preview, rec)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("메일 생성에 실패하였습니다: %v", err), http.StatusBadRequest)
+		log.Printf("EmailEMLHandler 오류 (%s): %v", rec.Email, err)
+		return
+	}
+	writeEML(w, reqData.Template+"-"+rec.Email, encoded.Data)
+}
+
+// writeEML sends a raw message as a .eml download.
+func writeEML(w http.ResponseWriter, name string, data []byte) {
+	w.Header().Set("Content-Type", "message/rfc822")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".eml"}))
+	_, _ = w.Write(data)
+}
+
+// jobMessage returns the archived message sent to the recipient given by the email query parameter.
+func (h *APIHandler) jobMessage(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	j, err := h.JobManager.Store.Get(jobID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("작업을 찾을 수 없습니다: %s", jobID), http.StatusNotFound)
+		return
+	}
+	to := r.URL.Query().Get("email")
+	for i, rec := range j.Recipients {
+		if !strings.EqualFold(job.BareAddress(rec.Email), job.BareAddress(to)) {
+			continue
+		}
+		data, err := h.JobManager.Store.Message(jobID, i)
+		if err == nil {
+			writeEML(w, jobID+"-"+rec.Email, data)
+			return
+		}
+		if !errors.Is(err, job.ErrNotFound) {
+			http.Error(w, "보관된 메일을 읽지 못하였습니다.", http.StatusInternalServerError)
+			log.Printf("jobMessage 오류 (%s, %s): %v", jobID, to, err)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("보관된 메일이 없습니다: %s", to), http.StatusNotFound)
+}
+
+func (h *APIHandler) JobsListHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	// ?status=scheduled 처럼 상태로 필터링할 수 있습니다.
+	status := job.Status(r.URL.Query().Get("status"))
+	jobs := h.JobManager.Store.List()
+	summaries := make([]job.Summary, 0, len(jobs))
+	for _, j := range jobs {
+		if status != "" && j.Status != status {
+			continue
+		}
+		summaries = append(summaries, j.Summary())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"jobs": summaries})
+}
+
+func (h *APIHandler) JobHandler(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/api/email/jobs/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "잘못된 URL 경로입니다.", http.StatusBadRequest)
+		return
+	}
+	jobID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if jobID == "" || strings.Contains(action, "/") {
+		http.Error(w, "유효한 작업 ID가 제공되지 않았습니다.", http.StatusBadRequest)
+		return
+	}
+
+	if action == "events" {
+		h.jobEvents(w, r, jobID)
+		return
+	}
+	if action == "eml" {
+		h.jobMessage(w, r, jobID)
+		return
+	}
+	if action == "" || action == "failed" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+			return
+		}
+		j, err := h.JobManager.Store.Get(jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("작업을 찾을 수 없습니다: %s", jobID), http.StatusNotFound)
+			return
+		}
+		response := map[string]interface{}{
+			"job":     j,
+			"summary": j.Summary(),
+		}
+		if action == "failed" {
+			failed := j.DeadLetters()
+			if failed == nil {
+				failed = []*job.Recipient{}
 			}
-			for i := 0; i < 10; i++ {
-				if err := h.SMTPClient.SendEmail([]string{rec.Email}, reqData.Subject, htm, attachments); err != nil {
-					log.Printf("이메일 발송 실패 (%s): %v", rec.Email, err)
-					time.Sleep(10 * time.Second)
-					continue
-				}
-				break
+			response = map[string]interface{}{
+				"job_id":     j.ID,
+				"recipients": failed,
 			}
-			log.Printf("이메일 발송 성공 (%s)", rec.Email)
 		}
-	}(reqData.Recipient)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+		return
+	}
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		j       *job.Job
+		err     error
+		message string
+	)
+	switch action {
+	case "cancel":
+		j, err = h.JobManager.CancelJob(jobID)
+		message = "발송 작업이 취소되었습니다."
+	case "pause":
+		j, err = h.JobManager.PauseJob(jobID)
+		message = "발송 작업이 일시 정지되었습니다."
+	case "resume":
+		j, err = h.JobManager.ResumeJob(jobID)
+		message = "발송 작업이 재개되었습니다."
+	case "retry":
+		// 재발송은 요청한 사용자가 원래 작업의 발신자로 발송할 권한이 있어야 합니다.
+		if original, getErr := h.JobManager.Store.Get(jobID); getErr == nil && !h.checkSender(w, r, original.Sender) {
+			return
+		}
+		j, err = h.JobManager.RetryFailed(jobID, h.sessionEmail(r))
+		if err == nil {
+			message = fmt.Sprintf("실패한 수신자 %d명에게 다시 발송합니다.", len(j.Recipients))
+		}
+	default:
+		http.Error(w, "지원하지 않는 작업입니다.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeJobError(w, jobID, err)
+		return
+	}
 	response := map[string]interface{}{
-		"message": fmt.Sprintf("총 %d명의 수신자에게 이메일을 발송합니다.", len(reqData.Recipient)),
+		"message": message,
+		"job_id":  j.ID,
+		"summary": j.Summary(),
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// jobEvents streams the progress of a job as Server-Sent Events. It starts with a snapshot
+// of the current counts and ends after the job's final summary event.
+func (h *APIHandler) jobEvents(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "스트리밍을 지원하지 않습니다.", http.StatusInternalServerError)
+		return
+	}
+	// Subscribe before reading the snapshot so no event between the two is lost.
+	events, unsubscribe := h.JobManager.Subscribe(jobID)
+	defer unsubscribe()
+	j, err := h.JobManager.Store.Get(jobID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("작업을 찾을 수 없습니다: %s", jobID), http.StatusNotFound)
+		return
+	}
+
+	// 서버의 WriteTimeout이 긴 스트림을 끊지 않도록 해제합니다.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	writeEvent := func(e job.Event) {
+		data, _ := json.Marshal(e)
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		flusher.Flush()
+	}
+	summary := j.Summary()
+	if j.Finished() {
+		writeEvent(job.Event{Type: job.EventSummary, JobID: j.ID, Status: j.Status, Summary: &summary, Time: time.Now()})
+		return
+	}
+	writeEvent(job.Event{Type: job.EventStatus, JobID: j.ID, Status: j.Status, Summary: &summary, Time: time.Now()})
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e := <-events:
+			writeEvent(e)
+			if e.Type == job.EventSummary {
+				return
+			}
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeJobError maps job manager errors to HTTP responses.
+func writeJobError(w http.ResponseWriter, jobID string, err error) {
+	switch {
+	case errors.Is(err, job.ErrNotFound):
+		http.Error(w, fmt.Sprintf("작업을 찾을 수 없습니다: %s", jobID), http.StatusNotFound)
+	case errors.Is(err, job.ErrInvalidState):
+		http.Error(w, "현재 작업 상태에서는 요청을 처리할 수 없습니다.", http.StatusConflict)
+	case errors.Is(err, job.ErrNoDeadLetters):
+		http.Error(w, "다시 발송할 실패 수신자가 없습니다.", http.StatusConflict)
+	default:
+		http.Error(w, "작업 상태 변경에 실패하였습니다.", http.StatusInternalServerError)
+		log.Printf("작업 %s 상태 변경 오류: %v", jobID, err)
+	}
+}
+
 func (h *APIHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
@@ -0,0 +1,261 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"mail-manager/internal/archive"
+	"mail-manager/internal/email"
+	"mail-manager/internal/job"
+	"mail-manager/internal/suppression"
+)
+
+// newTestHandler returns a handler whose jobs are delivered to a MemoryTransport, with
+// admin@example.com logged in.
+func newTestHandler(t *testing.T) (*APIHandler, *email.MemoryTransport) {
+	t.Helper()
+	dir := t.TempDir()
+	tmplDir := filepath.Join(dir, "templates")
+	if err := os.MkdirAll(tmplDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmplDir, "welcome.html"), []byte(`<p>Hello {{.name}} of {{property "team"}}</p>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm := email.NewTemplateManager(tmplDir, filepath.Join(dir, "images"))
+	if err := tm.LoadTemplate("welcome", "welcome.html"); err != nil {
+		t.Fatal(err)
+	}
+	store, err := job.NewStore(filepath.Join(dir, "jobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := email.NewMemoryTransport("noreply@example.com")
+	account := email.NewAccount(email.DefaultAccount, transport, email.RateLimit{}, email.RetryPolicy{})
+	h := &APIHandler{
+		TemplateManager: tm,
+		Sender:          transport,
+		JobManager:      job.NewManager(store, tm, account, 0),
+		CurrentUser: func(r *http.Request) (string, []string) {
+			return "admin@example.com", nil
+		},
+	}
+	return h, transport
+}
+
+// waitJob waits until the job has finished.
+func waitJob(t *testing.T, m *job.Manager, id string) *job.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, err := m.Store.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.Finished() {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not finish, status %s", id, j.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEmailHandlerDeliversJob(t *testing.T) {
+	h, transport := newTestHandler(t)
+	body := `{
+		"template": "welcome",
+		"subject": "Welcome",
+		"recipient": [
+			{"name": "Alice", "email": "alice@example.com", "custom": {"team": "Red"}},
+			{"name": "Bob", "email": "bob@example.com", "custom": {"team": "Blue"}}
+		],
+		"reply_to": ["help@example.com"],
+		"headers": {"X-Campaign": "spring"}
+	}`
+	rec := httptest.NewRecorder()
+	h.EmailHandler(rec, httptest.NewRequest(http.MethodPost, "/api/email", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.JobID == "" {
+		t.Fatalf("response has no job id: %v %s", err, rec.Body.String())
+	}
+
+	j := waitJob(t, h.JobManager, resp.JobID)
+	if j.Requester != "admin@example.com" {
+		t.Errorf("requester = %q", j.Requester)
+	}
+	for _, r := range j.Recipients {
+		if r.State != job.RecipientSent || r.MessageID == "" {
+			t.Errorf("recipient %s: state %s, message id %q", r.Email, r.State, r.MessageID)
+		}
+	}
+
+	messages := transport.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("captured %d messages, want 2", len(messages))
+	}
+	sort.Slice(messages, func(a, b int) bool { return messages[a].Message.To[0] < messages[b].Message.To[0] })
+	for i, want := range []struct{ to, html string }{
+		{"alice@example.com", "Hello Alice of Red"},
+		{"bob@example.com", "Hello Bob of Blue"},
+	} {
+		msg := messages[i].Message
+		if len(msg.To) != 1 || msg.To[0] != want.to {
+			t.Errorf("message %d to %v, want %s", i, msg.To, want.to)
+		}
+		if !strings.Contains(msg.HTML, want.html) {
+			t.Errorf("message %d html = %q, want %q", i, msg.HTML, want.html)
+		}
+		if msg.Subject != "Welcome" || msg.Headers["X-Campaign"] != "spring" || len(msg.ReplyTo) != 1 {
+			t.Errorf("message %d: subject %q, headers %v, reply-to %v", i, msg.Subject, msg.Headers, msg.ReplyTo)
+		}
+		if messages[i].Encoded.From != "noreply@example.com" {
+			t.Errorf("message %d envelope from = %q", i, messages[i].Encoded.From)
+		}
+	}
+}
+
+func TestEmailHandlerRejectsCopiesForLargeJobs(t *testing.T) {
+	h, transport := newTestHandler(t)
+	recipients := make([]RecipientInfo, job.MaxRecipientsWithCopies+1)
+	for i := range recipients {
+		recipients[i] = RecipientInfo{Email: "user@example.com"}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"template":  "welcome",
+		"subject":   "Welcome",
+		"recipient": recipients,
+		"cc":        []string{"boss@example.com"},
+	})
+	rec := httptest.NewRecorder()
+	h.EmailHandler(rec, httptest.NewRequest(http.MethodPost, "/api/email", strings.NewReader(string(data))))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if n := len(transport.Messages()); n != 0 {
+		t.Errorf("captured %d messages, want none", n)
+	}
+}
+
+func TestSuppressedAddresses(t *testing.T) {
+	h, transport := newTestHandler(t)
+	list, err := suppression.NewList(filepath.Join(t.TempDir(), "suppression.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.JobManager.Suppressions = list
+	for _, addr := range []string{"Bob@example.com", "admin@example.com"} {
+		if _, _, err := list.Add(suppression.Entry{Email: addr, Reason: suppression.ReasonBounce}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body := `{
+		"template": "welcome",
+		"subject": "Welcome",
+		"recipient": [
+			{"name": "Alice", "email": "alice@example.com", "custom": {"team": "Red"}},
+			{"name": "Bob", "email": "Bob <bob@example.com>", "custom": {"team": "Blue"}}
+		],
+		"dry_run": true
+	}`
+	rec := httptest.NewRecorder()
+	h.EmailHandler(rec, httptest.NewRequest(http.MethodPost, "/api/email", strings.NewReader(body)))
+	var dryRun struct {
+		Suppressed int                `json:"suppressed"`
+		Recipients []job.DryRunResult `json:"recipients"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&dryRun); err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.Suppressed != 1 || dryRun.Recipients[0].Suppressed != "" || dryRun.Recipients[1].Suppressed != suppression.ReasonBounce {
+		t.Errorf("dry run = %+v, want only bob suppressed", dryRun)
+	}
+
+	// The logged-in user's own address is suppressed, so the test mail is refused.
+	rec = httptest.NewRecorder()
+	h.EmailTestHandler(rec, httptest.NewRequest(http.MethodPost, "/api/email/test", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("test send status = %d, want 409: %s", rec.Code, rec.Body.String())
+	}
+	if n := len(transport.Messages()); n != 0 {
+		t.Errorf("captured %d messages, want none", n)
+	}
+}
+
+func TestEmailHandlerStoresBareAddresses(t *testing.T) {
+	h, _ := newTestHandler(t)
+	store, err := archive.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	h.JobManager.Archive = store
+	h.JobManager.ArchiveMessages = true
+
+	body := `{
+		"template": "welcome",
+		"subject": "Welcome",
+		"recipient": [{"email": "Bob <Bob@example.com>", "custom": {"team": "Blue"}}]
+	}`
+	rec := httptest.NewRecorder()
+	h.EmailHandler(rec, httptest.NewRequest(http.MethodPost, "/api/email", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	j := waitJob(t, h.JobManager, resp.JobID)
+	if r := j.Recipients[0]; r.Email != "Bob@example.com" || r.Name != "Bob" {
+		t.Errorf("recipient = %q <%s>, want Bob <Bob@example.com>", r.Name, r.Email)
+	}
+	if entries, _ := store.Search(archive.Query{Recipient: "bob@example.com"}); len(entries) != 1 || entries[0].Recipient != "Bob@example.com" {
+		t.Errorf("archive entries = %+v", entries)
+	}
+
+	for _, to := range []string{"bob@example.com", "Bob <bob@example.com>"} {
+		rec = httptest.NewRecorder()
+		h.JobHandler(rec, httptest.NewRequest(http.MethodGet, "/api/email/jobs/"+j.ID+"/eml?email="+url.QueryEscape(to), nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("eml for %q: status = %d: %s", to, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestEmailHandlerRejectsInvalidAddress(t *testing.T) {
+	h, transport := newTestHandler(t)
+	body := `{
+		"template": "welcome",
+		"subject": "Welcome",
+		"recipient": [{"name": "Alice", "email": "alice@example.com"}, {"name": "Bob", "email": "bob at example.com"}]
+	}`
+	rec := httptest.NewRecorder()
+	h.EmailHandler(rec, httptest.NewRequest(http.MethodPost, "/api/email", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if jobs := h.JobManager.Store.List(); len(jobs) != 0 {
+		t.Errorf("created %d jobs, want none", len(jobs))
+	}
+	if n := len(transport.Messages()); n != 0 {
+		t.Errorf("captured %d messages, want none", n)
+	}
+}
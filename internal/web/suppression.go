@@ -0,0 +1,161 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"mail-manager/internal/suppression"
+)
+
+// SuppressionsHandler lists the suppression list (GET, optionally filtered with ?reason=)
+// and adds an address to it (POST {"email", "reason", "detail"}). The reason defaults to manual.
+func (h *APIHandler) SuppressionsHandler(w http.ResponseWriter, r *http.Request) {
+	list := h.JobManager.Suppressions
+	if list == nil {
+		http.Error(w, "발송 제외 목록이 설정되지 않았습니다.", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		reason := suppression.Reason(r.URL.Query().Get("reason"))
+		entries := make([]suppression.Entry, 0)
+		for _, e := range list.List() {
+			if reason == "" || e.Reason == reason {
+				entries = append(entries, e)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"suppressions": entries})
+	case http.MethodPost:
+		var reqData struct {
+			Email  string             `json:"email"`
+			Reason suppression.Reason `json:"reason"`
+			Detail string             `json:"detail"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+			http.Error(w, "올바르지 않은 JSON 페이로드입니다.", http.StatusBadRequest)
+			return
+		}
+		addr, err := mail.ParseAddress(reqData.Email)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("잘못된 이메일 주소입니다: %s", reqData.Email), http.StatusBadRequest)
+			return
+		}
+		if reqData.Reason == "" {
+			reqData.Reason = suppression.ReasonManual
+		}
+		if !reqData.Reason.Valid() {
+			http.Error(w, fmt.Sprintf("알 수 없는 사유입니다: %s", reqData.Reason), http.StatusBadRequest)
+			return
+		}
+		entry, created, err := list.Add(suppression.Entry{
+			Email:     addr.Address,
+			Reason:    reqData.Reason,
+			Detail:    reqData.Detail,
+			CreatedBy: h.sessionEmail(r),
+		})
+		if err != nil {
+			http.Error(w, "발송 제외 목록을 저장하지 못하였습니다.", http.StatusInternalServerError)
+			log.Printf("SuppressionsHandler 오류 (%s): %v", addr.Address, err)
+			return
+		}
+		message := "발송 제외 목록에 추가하였습니다."
+		status := http.StatusCreated
+		if !created {
+			message = "이미 발송 제외 목록에 있는 주소입니다."
+			status = http.StatusOK
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     message,
+			"suppression": entry,
+		})
+	default:
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+	}
+}
+
+// SuppressionHandler reads (GET), changes the reason and detail of (PUT) or removes (DELETE)
+// the entry of /api/suppressions/{email}. Changing and removing entries is limited to
+// admins, since it lets mail reach an address that bounced or unsubscribed again.
+func (h *APIHandler) SuppressionHandler(w http.ResponseWriter, r *http.Request) {
+	list := h.JobManager.Suppressions
+	if list == nil {
+		http.Error(w, "발송 제외 목록이 설정되지 않았습니다.", http.StatusNotFound)
+		return
+	}
+	addr := strings.TrimPrefix(r.URL.Path, "/api/suppressions/")
+	if addr == "" || strings.Contains(addr, "/") {
+		http.Error(w, "유효한 이메일 주소가 제공되지 않았습니다.", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		entry, ok := list.Get(addr)
+		if !ok {
+			http.Error(w, fmt.Sprintf("발송 제외 목록에 없는 주소입니다: %s", addr), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"suppression": entry})
+	case http.MethodPut:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var reqData struct {
+			Reason suppression.Reason `json:"reason"`
+			Detail string             `json:"detail"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+			http.Error(w, "올바르지 않은 JSON 페이로드입니다.", http.StatusBadRequest)
+			return
+		}
+		if !reqData.Reason.Valid() {
+			http.Error(w, fmt.Sprintf("알 수 없는 사유입니다: %s", reqData.Reason), http.StatusBadRequest)
+			return
+		}
+		entry, err := list.Update(addr, reqData.Reason, reqData.Detail)
+		if !h.writeSuppressionError(w, addr, err) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     "발송 제외 사유를 변경하였습니다.",
+			"suppression": entry,
+		})
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if !h.writeSuppressionError(w, addr, list.Remove(addr)) {
+			return
+		}
+		log.Printf("발송 제외 목록에서 삭제 (%s): %s", h.sessionEmail(r), addr)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "발송 제외 목록에서 삭제하였습니다.",
+		})
+	default:
+		http.Error(w, "지원하지 않는 메서드입니다.", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeSuppressionError writes the response for a failed list change and reports whether err was nil.
+func (h *APIHandler) writeSuppressionError(w http.ResponseWriter, addr string, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, suppression.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("발송 제외 목록에 없는 주소입니다: %s", addr), http.StatusNotFound)
+		return false
+	}
+	http.Error(w, "발송 제외 목록을 저장하지 못하였습니다.", http.StatusInternalServerError)
+	log.Printf("SuppressionHandler 오류 (%s): %v", addr, err)
+	return false
+}